@@ -0,0 +1,71 @@
+package ashcache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec converts typed values to the bytes stored in the cache and back.
+// The encoded bytes are exactly what an entry weighs (see Entry.Weight), so codecs
+// must not return slices with spare capacity; otherwise memory limits are overestimated.
+type Codec[V any] interface {
+	Encode(value V) ([]byte, error)
+	Decode(data []byte) (V, error)
+}
+
+// JSONCodec encodes values by encoding/json.
+type JSONCodec[V any] struct{}
+
+func (JSONCodec[V]) Encode(value V) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return fit(data), nil
+}
+
+func (JSONCodec[V]) Decode(data []byte) (value V, err error) {
+	err = json.Unmarshal(data, &value)
+	return
+}
+
+// GobCodec encodes values by encoding/gob. Each value is encoded as a self-contained stream,
+// so type information is repeated per entry; prefer JSONCodec or a custom Codec for small values.
+type GobCodec[V any] struct{}
+
+func (GobCodec[V]) Encode(value V) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return fit(buf.Bytes()), nil
+}
+
+func (GobCodec[V]) Decode(data []byte) (value V, err error) {
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return
+}
+
+// BytesCodec is a passthrough codec for raw []byte values. Values are stored as is (no copy),
+// so callers must not mutate them after passing to or receiving from the cache.
+type BytesCodec struct{}
+
+func (BytesCodec) Encode(value []byte) ([]byte, error) { return value, nil }
+func (BytesCodec) Decode(data []byte) ([]byte, error)  { return data, nil }
+
+// StringCodec is a passthrough codec for string values.
+type StringCodec struct{}
+
+func (StringCodec) Encode(value string) ([]byte, error) { return fit([]byte(value)), nil }
+func (StringCodec) Decode(data []byte) (string, error)  { return string(data), nil }
+
+// fit returns a slice without spare capacity, so an entry weighs exactly the encoded size.
+func fit(data []byte) []byte {
+	if cap(data) == len(data) {
+		return data
+	}
+	out := make([]byte, len(data))
+	copy(out, data)
+	return out
+}
@@ -24,4 +24,12 @@ type Cache struct {
 	// It defines when and how cache entries are evicted to stay within memory limits.
 	// If nil, eviction is disabled and cache size is unbounded (not recommended).
 	Eviction *EvictionCfg `yaml:"eviction"`
+
+	// Negative configures caching of loader errors (tombstones) with a separate TTL.
+	// If nil, loader errors are not cached and every miss calls the loader again.
+	Negative *NegativeCfg `yaml:"negative"`
+
+	// StaleWhileRevalidate configures serving of expired payloads while they are refreshed in background.
+	// If nil, expired payloads are served until the lifetimer refreshes them.
+	StaleWhileRevalidate *SWRCfg `yaml:"stale_while_revalidate"`
 }
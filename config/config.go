@@ -7,8 +7,17 @@ import (
 )
 
 func (cfg *Cache) AdjustConfig() {
+	if cfg.DB.PinnedFraction <= 0 {
+		cfg.DB.PinnedFraction = DefaultPinnedFraction
+	}
+	cfg.DB.PinnedLimitBytes = int64(float64(cfg.DB.SizeBytes) * cfg.DB.PinnedFraction)
+
 	if cfg.Eviction.Enabled() {
 		cfg.Eviction.IsListing = cfg.Eviction.LRUMode == LRUModeListing
+		cfg.Eviction.IsWindowed = cfg.Eviction.LRUMode == LRUModeWindowed
+		if cfg.Eviction.WindowFraction <= 0 || cfg.Eviction.WindowFraction >= 1 {
+			cfg.Eviction.WindowFraction = DefaultWindowFraction
+		}
 		cfg.Eviction.SoftMemoryLimitBytes = int64(float64(cfg.DB.SizeBytes) * cfg.Eviction.SoftLimitCoefficient)
 	}
 
@@ -18,6 +27,33 @@ func (cfg *Cache) AdjustConfig() {
 		} else {
 			cfg.Lifetime.IsRemoveOnTTL = true
 		}
+		cfg.Lifetime.IsSliding = cfg.Lifetime.OnTTL == TTLModeSliding
+		cfg.Lifetime.IsKeptStale = cfg.Lifetime.OnTTL == TTLModeStale
+
+		if sie := cfg.Lifetime.StaleIfError; sie.Enabled() {
+			if sie.Backoff <= 0 {
+				sie.Backoff = DefaultStaleIfErrorBackoff
+			}
+			if sie.MaxBackoff <= 0 {
+				sie.MaxBackoff = DefaultStaleIfErrorMaxBackoff
+			}
+		}
+	}
+
+	if cfg.Negative.Enabled() && cfg.Negative.TTL <= 0 {
+		cfg.Negative.TTL = DefaultNegativeTTL
+	}
+
+	if cfg.StaleWhileRevalidate.Enabled() {
+		if cfg.StaleWhileRevalidate.StaleWindow <= 0 {
+			cfg.StaleWhileRevalidate.StaleWindow = DefaultSWRStaleWindow
+		}
+		if cfg.StaleWhileRevalidate.Workers <= 0 {
+			cfg.StaleWhileRevalidate.Workers = DefaultSWRWorkers
+		}
+		if cfg.StaleWhileRevalidate.QueueSize <= 0 {
+			cfg.StaleWhileRevalidate.QueueSize = DefaultSWRQueueSize
+		}
 	}
 }
 
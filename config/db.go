@@ -7,4 +7,12 @@ type DBCfg struct {
 	IsTelemetryLogsEnabled bool          `yaml:"stat_logs_enabled"`
 	TelemetryLogsInterval  time.Duration `yaml:"5s"`
 	CacheTimeEnabled       bool          `yaml:"cache_time_enabled"`
+	RetainKeys             bool          `yaml:"retain_keys"`     // keep original keys in entries (for DelPrefix and Scan)
+	PinnedFraction         float64       `yaml:"pinned_fraction"` // max share of size taken by pinned entries
+
+	// PinnedLimitBytes is derived from PinnedFraction and SizeBytes during initialization and is not read from YAML.
+	PinnedLimitBytes int64 // virtual: computed during init
 }
+
+// DefaultPinnedFraction is the share of db.size pinned entries may take if db.pinned_fraction is not set.
+const DefaultPinnedFraction = 0.1
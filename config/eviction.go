@@ -9,15 +9,36 @@ const (
 
 	// LRUModeListing evicts entries by iterating over the LRU list directly.
 	LRUModeListing LRUMode = "listing"
+
+	// LRUModeWindowed evicts entries by W-TinyLFU: a small admission window LRU in front of
+	// a segmented LRU (probation and protected), TinyLFU decides promotion from the window.
+	LRUModeWindowed LRUMode = "wtinylfu"
 )
 
+// DefaultWindowFraction is the share of entries kept in the admission window if eviction.window_fraction is not set.
+const DefaultWindowFraction = 0.01
+
 type EvictionCfg struct {
 	// LRUMode defines the LRU eviction mode.
 	// Supported values:
 	//   - "sampling": eviction is based on sampling a subset of entries
 	//   - "listing":  eviction iterates over the LRU list directly
+	//   - "wtinylfu": W-TinyLFU, admission window + segmented LRU (see WindowFraction)
 	LRUMode LRUMode `yaml:"mode"`
 
+	// WindowFraction defines the share of entries kept in the admission window in "wtinylfu" mode.
+	// New entries always enter the window; they compete for a place in the main space once they leave it.
+	// A bigger window favors recency, a smaller one favors frequency. Must be within (0, 1).
+	//
+	// Example:
+	//   WindowFraction: 0.01 // 1% window, 99% segmented LRU
+	WindowFraction float64 `yaml:"window_fraction"`
+
+	// AdaptiveWindow enables hill climbing of the window share in "wtinylfu" mode: the hit rate is sampled
+	// per period of requests and the window/main split moves toward whichever direction improves it.
+	// WindowFraction is the starting point then.
+	AdaptiveWindow bool `yaml:"adaptive_window"`
+
 	// SoftLimitCoefficient defines the soft memory usage threshold as a fraction of cfg.DB.SizeBytes.
 	// When memory usage exceeds this limit, eviction may start proactively.
 	//
@@ -50,6 +71,9 @@ type EvictionCfg struct {
 	// It is used internally as a fast-path flag to avoid repeated comparisons.
 	// This field is not read from YAML.
 	IsListing bool // virtual: computed during init
+
+	// IsWindowed is derived from LRUMode during initialization, like IsListing.
+	IsWindowed bool // virtual: computed during init
 }
 
 func (cfg *EvictionCfg) Enabled() bool {
@@ -7,6 +7,11 @@ type TTLMode string
 var (
 	TTLModeRemove  TTLMode = "remove"
 	TTLModeRefresh TTLMode = "refresh"
+	// TTLModeSliding removes an item once it has not been accessed for TTL (each access renews the lifetime).
+	TTLModeSliding TTLMode = "sliding"
+	// TTLModeStale keeps an item past TTL and serves it as stale until eviction pushes it out
+	// or the application overwrites it.
+	TTLModeStale TTLMode = "stale"
 )
 
 type LifetimerCfg struct {
@@ -39,6 +44,22 @@ type LifetimerCfg struct {
 	//   Beta: 0.4
 	Beta float64 `yaml:"beta"` // Recommended range: (0, 1].
 
+	// RefreshTimeout bounds a single background refresh: the loader receives a context that is canceled
+	// after this duration (or when the lifetimer is closed, whichever comes first).
+	// Zero means no per-refresh timeout.
+	// Example: "5s".
+	RefreshTimeout time.Duration `yaml:"refresh_timeout"`
+
+	// StaleIfError configures backoff of failed refreshes and the grace period of serving the last good payload.
+	// If nil, a failed refresh is retried on the next scan and the last good payload is served indefinitely.
+	StaleIfError *StaleIfErrorCfg `yaml:"stale_if_error"`
+
+	// MaxStale is a hard limit on how long after the last stored payload an item refreshed on TTL may be served,
+	// whether its refreshes fail or never get scheduled. Past it, a read reloads the item synchronously
+	// and the lifetimer evicts it. It should be greater than TTL. Zero means no limit.
+	// Example: "1h".
+	MaxStale time.Duration `yaml:"max_stale"`
+
 	// StochasticBetaRefreshEnabled enables stochastic (Beta-based) scheduling for refreshes.
 	// When disabled, refresh scheduling falls back to the deterministic policy (e.g., Coefficient).
 	StochasticBetaRefreshEnabled bool `yaml:"stochastic_refresh_enabled"`
@@ -51,6 +72,12 @@ type LifetimerCfg struct {
 	// IsRemoveOnTTL is derived from OnTTL during initialization and is not read from YAML.
 	// It is used internally as a fast path to decide whether an item should be removed at TTL.
 	IsRemoveOnTTL bool // virtual: computed during init
+
+	// IsSliding is derived from OnTTL during initialization and is not read from YAML.
+	IsSliding bool // virtual: computed during init
+
+	// IsKeptStale is derived from OnTTL during initialization and is not read from YAML.
+	IsKeptStale bool // virtual: computed during init
 }
 
 func (cfg *LifetimerCfg) Enabled() bool {
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultNegativeTTL is used when NegativeCfg.TTL is not set.
+const DefaultNegativeTTL = time.Second
+
+// NegativeCfg configures negative caching: loader errors matching it are stored as tombstones
+// and replayed to callers until the tombstone expires, instead of calling the loader again.
+type NegativeCfg struct {
+	// TTL is how long a cached error is replayed. Keep it short compared to Lifetime.TTL.
+	// Zero falls back to DefaultNegativeTTL.
+	// Example: "500ms".
+	TTL time.Duration `yaml:"ttl"`
+
+	// Errors are matched against loader errors by errors.Is.
+	// This field is not read from YAML.
+	Errors []error `yaml:"-"`
+
+	// Match reports whether a loader error should be cached; it is checked besides Errors.
+	// This field is not read from YAML.
+	Match func(err error) bool `yaml:"-"`
+}
+
+func (cfg *NegativeCfg) Enabled() bool {
+	return cfg != nil
+}
+
+// IsCacheable reports whether the loader error should be cached. Context errors are never cached:
+// they describe the caller rather than the key. If neither Errors nor Match is set, any other error is cached.
+func (cfg *NegativeCfg) IsCacheable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if len(cfg.Errors) == 0 && cfg.Match == nil {
+		return true
+	}
+	for _, target := range cfg.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return cfg.Match != nil && cfg.Match(err)
+}
@@ -0,0 +1,33 @@
+package config
+
+import "time"
+
+const (
+	// DefaultStaleIfErrorBackoff is used when StaleIfErrorCfg.Backoff is not set.
+	DefaultStaleIfErrorBackoff = time.Second
+	// DefaultStaleIfErrorMaxBackoff is used when StaleIfErrorCfg.MaxBackoff is not set.
+	DefaultStaleIfErrorMaxBackoff = time.Minute
+)
+
+// StaleIfErrorCfg configures serving of the last good payload while background refreshes fail.
+// A failed refresh pushes the next attempt of the entry out by an exponential backoff; once the entry
+// has been served stale longer than GracePeriod, it is removed, so a next read reloads it synchronously.
+type StaleIfErrorCfg struct {
+	// GracePeriod is how long after TTL has elapsed the last good payload may be served while refreshes fail.
+	// Example: "10m".
+	GracePeriod time.Duration `yaml:"grace_period"`
+
+	// Backoff is the delay of a refresh retry after the first failure; it doubles on each next failure.
+	// Zero falls back to DefaultStaleIfErrorBackoff.
+	// Example: "1s".
+	Backoff time.Duration `yaml:"backoff"`
+
+	// MaxBackoff caps the retry delay.
+	// Zero falls back to DefaultStaleIfErrorMaxBackoff.
+	// Example: "1m".
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+func (cfg *StaleIfErrorCfg) Enabled() bool {
+	return cfg != nil
+}
@@ -0,0 +1,35 @@
+package config
+
+import "time"
+
+const (
+	// DefaultSWRStaleWindow is used when SWRCfg.StaleWindow is not set.
+	DefaultSWRStaleWindow = 30 * time.Second
+	// DefaultSWRWorkers is used when SWRCfg.Workers is not set.
+	DefaultSWRWorkers = 4
+	// DefaultSWRQueueSize is used when SWRCfg.QueueSize is not set.
+	DefaultSWRQueueSize = 1024
+)
+
+// SWRCfg configures stale-while-revalidate on the read path for entries in refresh TTL mode.
+// Within the stale window an expired payload is served and exactly one background refresh is triggered;
+// beyond the window a read blocks on a synchronous reload.
+type SWRCfg struct {
+	// StaleWindow is how long after TTL has elapsed a payload may still be served while it is being revalidated.
+	// Example: "30s". Zero falls back to DefaultSWRStaleWindow: with no window every stale read would block
+	// on a synchronous reload, which is SWR turned off.
+	StaleWindow time.Duration `yaml:"stale_window"`
+
+	// Workers is the number of background revalidation workers.
+	// Zero falls back to DefaultSWRWorkers.
+	Workers int `yaml:"workers"`
+
+	// QueueSize bounds pending revalidations. When the queue is full, a stale read doesn't schedule
+	// a revalidation, a next stale read tries again.
+	// Zero falls back to DefaultSWRQueueSize.
+	QueueSize int `yaml:"queue_size"`
+}
+
+func (cfg *SWRCfg) Enabled() bool {
+	return cfg != nil
+}
@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"errors"
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
+)
+
+// ErrBatchLoaderMismatch is reported for each missed key when a batch loader
+// returns a number of results which doesn't match the number of requested keys.
+var ErrBatchLoaderMismatch = errors.New("batch loader returned results of unexpected length")
+
+// BatchLoader loads a batch of missed keys at once. payloads[i] and errs[i] must match keys[i];
+// errs may be nil if all keys were loaded. items[i] may be used to configure the entry of keys[i].
+type BatchLoader = func(keys []string, items []pubmodel.Item) (payloads [][]byte, errs []error)
+
+// GetMany returns payloads of the keys in the input order. Hits are looked up with one read lock per shard;
+// all missed keys (deduplicated) are handed to a single loader call. errs[i] reports the loader error of keys[i].
+// The loader is kept as the refresh callback of each loaded entry and then receives the refreshed key only.
+func (c *Cache) GetMany(keys []string, loader BatchLoader) (data [][]byte, errs []error) {
+	data = make([][]byte, len(keys))
+	errs = make([]error, len(keys))
+
+	hashes := c.hashMany(keys)
+	found := make([]*model.Entry, len(keys))
+	c.db.GetMany(hashes, found)
+
+	var (
+		hits     = make([]uint64, 0, len(keys))
+		missed   []int                    // position of the first occurrence of each missed key
+		missedAt = make([]int, len(keys)) // position in missed by input position (-1 on hit)
+		missedBy map[string]int
+	)
+	for i, entry := range found {
+		if entry != nil && !c.expireOnRead(entry) && c.isServable(entry) {
+			c.sampleRequest(true)
+			c.markTouched(entry)
+			hits = append(hits, hashes[i].Value())
+			data[i], errs[i] = c.hit(entry)
+			missedAt[i] = -1
+			continue
+		}
+		c.sampleRequest(false)
+		if missedBy == nil {
+			missedBy = make(map[string]int)
+		}
+		j, dup := missedBy[keys[i]]
+		if !dup {
+			j = len(missed)
+			missedBy[keys[i]] = j
+			missed = append(missed, i)
+		}
+		missedAt[i] = j
+	}
+	// move hits to front in LRU with one lock per shard
+	c.db.TouchMany(hits)
+
+	if len(missed) == 0 {
+		return data, errs
+	}
+
+	payloads, loadErrs := c.loadMany(keys, hashes, missed, loader)
+	for i, j := range missedAt {
+		if j >= 0 {
+			data[i], errs[i] = payloads[j], loadErrs[j]
+		}
+	}
+	return data, errs
+}
+
+// SetMany stores values[i] under keys[i] without a loader; stored[i] is false if admission control
+// rejected values[i]. Keys are grouped by shard, so each shard is locked once per batch.
+// Panics if keys and values have different lengths.
+func (c *Cache) SetMany(keys []string, values [][]byte, opts ...pubmodel.Option) (stored []bool) {
+	if len(keys) != len(values) {
+		panic("cache: SetMany keys and values must have the same length")
+	}
+	entries := make([]*model.Entry, len(keys))
+	for i, key := range keys {
+		entries[i] = c.newWrittenEntry(model.NewKey(key), rawOf(key), values[i], opts)
+	}
+	return c.setMany(entries)
+}
+
+// DelMany removes the keys and returns the number of removed entries.
+// Keys are grouped by shard, so each shard is locked once per batch.
+func (c *Cache) DelMany(keys []string) (removed int64) {
+	_, removed = c.db.RemoveMany(c.hashMany(keys))
+	return removed
+}
+
+// loadMany loads missed keys (given by their positions in keys) by a single loader call
+// and publishes the loaded entries. Results match the missed slice.
+func (c *Cache) loadMany(
+	keys []string,
+	hashes []*pubmodel.Key,
+	missed []int,
+	loader BatchLoader,
+) (payloads [][]byte, errs []error) {
+	var (
+		missedKeys = make([]string, len(missed))
+		items      = make([]pubmodel.Item, len(missed))
+		entries    = make([]*model.Entry, len(missed))
+	)
+	for j, i := range missed {
+		missedKeys[j] = keys[i]
+		entries[j] = c.newEntry(hashes[i], rawOf(keys[i]), c.cfgTTLNanoseconds(), c.cfgTTLModeIsRemoveOnTTL())
+		items[j] = entries[j]
+	}
+
+	payloads, errs = loader(missedKeys, items)
+	if len(payloads) != len(missed) || (errs != nil && len(errs) != len(missed)) {
+		payloads, errs = make([][]byte, len(missed)), make([]error, len(missed))
+		for j := range errs {
+			errs[j] = ErrBatchLoaderMismatch
+		}
+		return payloads, errs
+	}
+	if errs == nil {
+		errs = make([]error, len(missed))
+	}
+
+	loaded := entries[:0]
+	for j, entry := range entries {
+		if errs[j] != nil {
+			c.storeNegative(hashes[missed[j]], rawOf(missedKeys[j]), errs[j])
+			continue
+		}
+		entry.SetPayload(payloads[j])
+		// this value could be changed in loader; so set after exec. of loader
+		if entry.IsRemoveByTTL() {
+			entry.SetCallback(c.removeCallback)
+		} else {
+			entry.SetCallback(batchRefreshCallback(missedKeys[j], loader))
+		}
+		loaded = append(loaded, entry)
+	}
+	c.setMany(loaded)
+
+	return payloads, errs
+}
+
+// setMany is the batch counterpart of set. New keys pass admission control first, then all keys are written
+// (inserted, replaced, renewed or updated in place) with one lock per shard.
+func (c *Cache) setMany(entries []*model.Entry) (persisted []bool) {
+	persisted = make([]bool, len(entries))
+	keys := make([]*pubmodel.Key, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.Key()
+		c.admitter.Record(keys[i].Value())
+	}
+
+	found := make([]*model.Entry, len(entries))
+	c.db.GetMany(keys, found)
+
+	admitted := make([]bool, len(entries))
+	for i := range entries {
+		admitted[i] = found[i] != nil || c.admit(keys[i].Value())
+	}
+
+	var renewed []*model.Entry
+	c.db.ComputeMany(keys, func(i int, cur *model.Entry) *model.Entry {
+		in := entries[i]
+		switch {
+		case cur == nil:
+			if !admitted[i] {
+				return nil
+			}
+			persisted[i] = true
+			return in
+		case isReplacedWhole(cur, in):
+			if !isReplaceable(cur, in) {
+				return cur
+			}
+			persisted[i] = true
+			return in
+		case cur.IsTheSamePayload(in):
+			c.markRenewed(cur, in)
+			renewed = append(renewed, cur)
+		default:
+			c.overwrite(cur, in)
+		}
+		if in.IsPinned() {
+			cur.SetPinned(true) // charged by the map (see repin)
+		}
+		persisted[i] = true
+		return cur
+	})
+	// moved in LRU by the shard
+	for _, entry := range renewed {
+		c.markTouched(entry)
+	}
+
+	return persisted
+}
+
+func (c *Cache) hashMany(keys []string) (hashes []*pubmodel.Key) {
+	hashes = make([]*pubmodel.Key, len(keys))
+	for i, key := range keys {
+		hashes[i] = model.NewKey(key)
+	}
+	return
+}
+
+// batchRefreshCallback adapts a batch loader to refresh a single key.
+func batchRefreshCallback(key string, loader BatchLoader) model.TTLCallback {
+	return func(item pubmodel.Item) ([]byte, error) {
+		payloads, errs := loader([]string{key}, []pubmodel.Item{item})
+		if len(payloads) != 1 || (errs != nil && len(errs) != 1) {
+			return nil, ErrBatchLoaderMismatch
+		}
+		if errs != nil && errs[0] != nil {
+			return nil, errs[0]
+		}
+		return payloads[0], nil
+	}
+}
@@ -2,89 +2,307 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"github.com/Borislavv/go-ash-cache/config"
 	"github.com/Borislavv/go-ash-cache/internal/cache/db"
 	"github.com/Borislavv/go-ash-cache/internal/cache/db/bloom"
 	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	"github.com/Borislavv/go-ash-cache/internal/cache/flight"
+	"github.com/Borislavv/go-ash-cache/internal/shared/pool"
 	pubmodel "github.com/Borislavv/go-ash-cache/model"
+	"iter"
 	"log/slog"
+	"math"
 	"runtime"
+	"time"
+	"unsafe"
 )
 
 const shardsSample, keysSample, spinsBackoff = 2, 8, 32
 
 type Cacher interface {
 	Get(key string, callback func(item pubmodel.Item) ([]byte, error)) (data []byte, err error)
+	GetCtx(ctx context.Context, key string, callback func(ctx context.Context, item pubmodel.Item) ([]byte, error)) (data []byte, err error)
+	GetWithState(key string, callback func(item pubmodel.Item) ([]byte, error)) (data []byte, state pubmodel.State, err error)
+	GetWithMeta(key string, callback func(item pubmodel.Item) ([]byte, error)) (data []byte, meta pubmodel.Meta, err error)
+	GetWithVersion(key string, callback func(item pubmodel.Item) ([]byte, error)) (data []byte, version uint64, err error)
+	Set(key string, value []byte, opts ...pubmodel.Option) (stored bool)
+	SetWithTTL(key string, value []byte, ttl time.Duration) (stored bool)
+	Add(key string, value []byte, opts ...pubmodel.Option) (stored bool)
+	Peek(key string) (data []byte, ok bool)
+	Has(key string) (ok bool)
+	GetBytes(key []byte, callback func(item pubmodel.Item) ([]byte, error)) (data []byte, err error)
+	SetBytes(key []byte, value []byte, opts ...pubmodel.Option) (stored bool)
+	AddBytes(key []byte, value []byte, opts ...pubmodel.Option) (stored bool)
+	PeekBytes(key []byte) (data []byte, ok bool)
+	HasBytes(key []byte) (ok bool)
+	DelBytes(key []byte) (ok bool)
+	GetMany(keys []string, loader BatchLoader) (data [][]byte, errs []error)
+	SetMany(keys []string, values [][]byte, opts ...pubmodel.Option) (stored []bool)
+	DelMany(keys []string) (removed int64)
+	DelPrefix(prefix string) (removed int64, err error)
+	Scan(cursor uint64, match string, count int) (keys []string, next uint64, err error)
+	InvalidateTag(tag string) (removed int64)
+	InvalidateTags(tags ...string) (removed int64)
+	Compute(key string, fn func(old []byte, exists bool) (new []byte, op pubmodel.Op)) (value []byte, ok bool)
+	CompareAndSet(key string, expected uint64, value []byte, opts ...pubmodel.Option) (stored bool)
+	CompareAndDelete(key string, expected uint64) (deleted bool)
 	CacheMetrics() (admissionAllowed, admissionNotAllowed, hardEvictedItems, hardEvictedBytes int64)
+	FlightMetrics() (coalesced int64)
+	NegativeMetrics() (hits, stored int64)
+	SWRMetrics() (staleHits, revalidated, dropped int64)
+	StaleIfErrorMetrics() (refreshFailures, graceExpired int64)
+	RefreshFailures(key string) (failures int32, ok bool)
+	CollisionMetrics() (collisions int64)
+	ExpiryMetrics() (lazyExpired int64)
+	MaxStaleMetrics() (evicted int64)
+	ClimberMetrics() (grown, shrunk, restarts int64)
+	WindowSplit() (window, sampledHitRate float64)
+	TTL(key string) time.Duration
+	Expire(key string, d time.Duration) (ok bool)
+	ExpireAt(key string, t time.Time) (ok bool)
+	Persist(key string) (ok bool)
 	Around(ctx context.Context, fn func(item pubmodel.CacheItem) bool, rw bool)
+	All() iter.Seq2[pubmodel.Key, pubmodel.ItemView]
+	AllFunc(filter func(key pubmodel.Key, item pubmodel.ItemView) bool) iter.Seq2[pubmodel.Key, pubmodel.ItemView]
 	Del(key string) (ok bool)
 	Clear()
+	Pin(key string) (ok bool)
+	Unpin(key string) (ok bool)
 	Len() int64
 	Mem() int64
+	PinnedMem() int64
 }
 
 // Cache respects given ctx.
 type Cache struct {
-	admitter bloom.AdmissionControl
-	cfg      *config.Cache
-	db       *db.Map
-	logger   *slog.Logger
-	counters *counters
+	admitter    bloom.AdmissionControl
+	cfg         *config.Cache
+	db          *db.Map
+	flight      *flight.Group
+	revalidator *pool.Pool // nil if stale-while-revalidate is disabled
+	climber     *climber   // nil unless eviction.adaptive_window is enabled in wtinylfu mode
+	logger      *slog.Logger
+	counters    *counters
 }
 
 func New(ctx context.Context, cfg *config.Cache, logger *slog.Logger) *Cache {
-	return &Cache{
+	c := &Cache{
 		cfg:      cfg,
 		logger:   logger,
 		counters: newCounters(),
 		db:       db.NewMap(ctx, cfg),
+		flight:   flight.New(),
 		admitter: bloom.NewAdmissionControl(cfg.AdmissionControl),
 	}
+	if cfg.StaleWhileRevalidate.Enabled() {
+		c.revalidator = pool.New(ctx, cfg.StaleWhileRevalidate.Workers, cfg.StaleWhileRevalidate.QueueSize)
+	}
+	c.db.UseAdmitter(c.admitter)
+	if c.isWindowed() && cfg.Eviction.AdaptiveWindow {
+		c.climber = newClimber(c.db)
+	}
+	return c
 }
 
 func (c *Cache) Get(key string, callback func(item pubmodel.Item) ([]byte, error)) (data []byte, err error) {
-	k := model.NewKey(key)
-	if entry, ok := c.get(k.Value()); ok {
-		if entry.Key().IsTheSame(k) {
-			return entry.PayloadBytes(), nil
-		}
-		// hash collision
+	k := model.KeyOfString(key)
+	if entry, ok := c.lookup(&k); ok {
+		return c.hit(entry)
 	}
+	return c.miss(context.Background(), k, rawOf(key), wrapCallback(callback))
+}
 
-	entry := model.NewEntry(k, c.cfgTTLNanoseconds(), c.cfgTTLModeIsRemoveOnTTL())
+// GetBytes is Get for keys held as bytes. The key is hashed as is, without conversion to string;
+// the hit path does not allocate.
+func (c *Cache) GetBytes(key []byte, callback func(item pubmodel.Item) ([]byte, error)) (data []byte, err error) {
+	k := model.KeyOf(key)
+	if entry, ok := c.lookup(&k); ok {
+		return c.hit(entry)
+	}
+	return c.miss(context.Background(), k, key, wrapCallback(callback))
+}
 
-	// compute response
-	payload, err := callback(entry)
-	if err != nil {
-		return payload, err
+// GetCtx is a context-aware Get: ctx is passed to the callback and bounds the time spent waiting
+// for a concurrent loader call of the same key. The callback is kept as the refresh callback;
+// on refresh it receives a context derived from the lifetimer's one.
+func (c *Cache) GetCtx(
+	ctx context.Context,
+	key string,
+	callback func(ctx context.Context, item pubmodel.Item) ([]byte, error),
+) (data []byte, err error) {
+	k := model.KeyOfString(key)
+	if entry, ok := c.lookup(&k); ok {
+		return c.hit(entry)
 	}
-	entry.SetPayload(payload)
+	return c.miss(ctx, k, rawOf(key), callback)
+}
 
-	// this value could be changed in callback; so set after exec. of callback(entry)
-	if entry.IsRemoveByTTL() {
-		entry.SetCallback(c.removeCallback)
-	} else {
-		entry.SetCallback(callback)
+// GetWithState is Get which also tells whether the returned payload is fresh or stale
+// (served past its TTL while it's being refreshed).
+func (c *Cache) GetWithState(
+	key string,
+	callback func(item pubmodel.Item) ([]byte, error),
+) (data []byte, state pubmodel.State, err error) {
+	k := model.KeyOfString(key)
+	if entry, ok := c.lookup(&k); ok {
+		data, err = c.hit(entry)
+		return data, stateOf(entry), err
 	}
+	data, err = c.miss(context.Background(), k, rawOf(key), wrapCallback(callback))
+	return data, pubmodel.StateFresh, err
+}
 
-	// publish entry to common access; after this moment all accesses should be concurrent safe
-	c.set(entry)
+// GetWithMeta is Get which also returns metadata stored along with the payload (see model.Item.SetMeta).
+func (c *Cache) GetWithMeta(
+	key string,
+	callback func(item pubmodel.Item) ([]byte, error),
+) (data []byte, meta pubmodel.Meta, err error) {
+	k := model.KeyOfString(key)
+	if entry, ok := c.lookup(&k); ok {
+		return c.withMeta(entry)
+	}
+	if data, err = c.miss(context.Background(), k, rawOf(key), wrapCallback(callback)); err != nil {
+		return data, nil, err
+	}
+	// the loaded entry may be refreshed already: return its payload along with its metadata
+	if entry, ok := c.peekValue(&k); ok {
+		return c.withMeta(entry)
+	}
+	return data, nil, nil
+}
 
-	return payload, err
+// GetWithVersion is Get which also returns the version of the payload for CompareAndSet and CompareAndDelete.
+// The version is 0 for a cached error and for a loaded payload which was not stored.
+func (c *Cache) GetWithVersion(
+	key string,
+	callback func(item pubmodel.Item) ([]byte, error),
+) (data []byte, version uint64, err error) {
+	k := model.KeyOfString(key)
+	if entry, ok := c.lookup(&k); ok {
+		return c.versioned(entry)
+	}
+	if data, err = c.miss(context.Background(), k, rawOf(key), wrapCallback(callback)); err != nil {
+		return data, 0, err
+	}
+	// the loaded entry may be updated already: return its payload along with its version
+	if entry, ok := c.peekValue(&k); ok {
+		return c.versioned(entry)
+	}
+	return data, 0, nil
 }
 
-func (c *Cache) Del(key string) bool {
-	k := model.NewKey(key)
+// Set stores the value under the key without a loader. Returns false if admission control rejected the value.
+func (c *Cache) Set(key string, value []byte, opts ...pubmodel.Option) (stored bool) {
+	return c.set(c.newWrittenEntry(model.NewKey(key), rawOf(key), value, opts))
+}
 
-	if entry, ok := c.get(k.Value()); ok {
-		if entry.Key().IsTheSame(k) {
-			c.db.Remove(entry.Key().Value())
-			return ok
+// SetBytes is Set for keys held as bytes.
+func (c *Cache) SetBytes(key []byte, value []byte, opts ...pubmodel.Option) (stored bool) {
+	k := model.KeyOf(key)
+	return c.set(c.newWrittenEntry(&k, key, value, opts))
+}
+
+// SetWithTTL is a shortcut for Set(key, value, pubmodel.WithTTL(ttl)).
+func (c *Cache) SetWithTTL(key string, value []byte, ttl time.Duration) (stored bool) {
+	return c.Set(key, value, pubmodel.WithTTL(ttl))
+}
+
+// Add stores the value only if the key is absent. Returns false if the key
+// already exists or admission control rejected the value.
+func (c *Cache) Add(key string, value []byte, opts ...pubmodel.Option) (stored bool) {
+	return c.add(c.newWrittenEntry(model.NewKey(key), rawOf(key), value, opts))
+}
+
+// AddBytes is Add for keys held as bytes.
+func (c *Cache) AddBytes(key []byte, value []byte, opts ...pubmodel.Option) (stored bool) {
+	k := model.KeyOf(key)
+	return c.add(c.newWrittenEntry(&k, key, value, opts))
+}
+
+// Compute atomically reads and modifies the value of the key. fn receives the current value (exists is false
+// for an absent key or a cached error) and tells whether to keep it, replace it with new or delete the key.
+// fn runs under the lock of the owning shard, so it must be short and must not call the cache;
+// old is the stored slice and must not be modified.
+// Returns the value held by the key afterward; ok is false if the key is absent (deleted, never inserted
+// or rejected by admission control).
+func (c *Cache) Compute(key string, fn func(old []byte, exists bool) (new []byte, op pubmodel.Op)) (value []byte, ok bool) {
+	return c.compute(model.NewKey(key), rawOf(key), fn)
+}
+
+// CompareAndSet stores the value only if the version of the key is still the expected one
+// (0 expects the key to be absent). Returns false if the entry changed in between
+// or admission control rejected the value.
+func (c *Cache) CompareAndSet(key string, expected uint64, value []byte, opts ...pubmodel.Option) (stored bool) {
+	return c.compareAndSet(c.newWrittenEntry(model.NewKey(key), rawOf(key), value, opts), expected)
+}
+
+// CompareAndDelete removes the key only if its version is still the expected one.
+func (c *Cache) CompareAndDelete(key string, expected uint64) (deleted bool) {
+	c.db.Compute(model.NewKey(key), func(cur *model.Entry) *model.Entry {
+		if expected == 0 || versionOf(cur) != expected {
+			return cur
 		}
-		// hash collision
+		deleted = true
+		return nil
+	})
+	return deleted
+}
+
+// InvalidateTag removes all entries tagged by the tag (see model.Item.AddTags) and returns their number.
+func (c *Cache) InvalidateTag(tag string) (removed int64) {
+	_, removed = c.db.InvalidateTag(tag)
+	return removed
+}
+
+// InvalidateTags removes all entries tagged by any of the tags and returns their number.
+func (c *Cache) InvalidateTags(tags ...string) (removed int64) {
+	_, removed = c.db.InvalidateTags(tags...)
+	return removed
+}
+
+// Peek returns the cached payload without running a loader. It is not an access:
+// the entry is neither moved in LRU, nor recorded by admission control, nor queued on refresh.
+func (c *Cache) Peek(key string) (data []byte, ok bool) {
+	k := model.KeyOfString(key)
+	if entry, found := c.peekValue(&k); found {
+		return entry.PayloadBytes(), true
 	}
+	return nil, false
+}
 
-	return true
+// PeekBytes is Peek for keys held as bytes; it does not allocate.
+func (c *Cache) PeekBytes(key []byte) (data []byte, ok bool) {
+	k := model.KeyOf(key)
+	if entry, found := c.peekValue(&k); found {
+		return entry.PayloadBytes(), true
+	}
+	return nil, false
+}
+
+// Has reports whether the key is cached. Like Peek, it leaves no access trace.
+func (c *Cache) Has(key string) (ok bool) {
+	k := model.KeyOfString(key)
+	_, ok = c.peekValue(&k)
+	return ok
+}
+
+// HasBytes is Has for keys held as bytes; it does not allocate.
+func (c *Cache) HasBytes(key []byte) (ok bool) {
+	k := model.KeyOf(key)
+	_, ok = c.peekValue(&k)
+	return ok
+}
+
+func (c *Cache) Del(key string) bool {
+	k := model.KeyOfString(key)
+	return c.del(&k)
+}
+
+// DelBytes is Del for keys held as bytes; it does not allocate.
+func (c *Cache) DelBytes(key []byte) bool {
+	k := model.KeyOf(key)
+	return c.del(&k)
 }
 
 func (c *Cache) Len() int64 { return c.db.Len() }
@@ -95,6 +313,64 @@ func (c *Cache) CacheMetrics() (admissionAllowed, admissionNotAllowed, hardEvict
 	return c.counters.snapshot()
 }
 
+func (c *Cache) FlightMetrics() (coalesced int64) {
+	return c.counters.coalesced.Load()
+}
+
+func (c *Cache) NegativeMetrics() (hits, stored int64) {
+	return c.counters.negativeHits.Load(), c.counters.negativeStored.Load()
+}
+
+func (c *Cache) SWRMetrics() (staleHits, revalidated, dropped int64) {
+	return c.counters.staleHits.Load(), c.counters.revalidated.Load(), c.counters.revalidationsDropped.Load()
+}
+
+func (c *Cache) StaleIfErrorMetrics() (refreshFailures, graceExpired int64) {
+	return c.counters.refreshFailures.Load(), c.counters.graceExpired.Load()
+}
+
+// CollisionMetrics returns the number of keys stored in a slot already occupied by a different key.
+func (c *Cache) CollisionMetrics() (collisions int64) {
+	return c.db.Collisions()
+}
+
+// ExpiryMetrics returns the number of expired entries removed by reads ahead of the lifetimer.
+func (c *Cache) ExpiryMetrics() (lazyExpired int64) {
+	return c.counters.lazyExpired.Load()
+}
+
+// MaxStaleMetrics returns the number of entries evicted by the lifetimer beyond lifetime.max_stale.
+func (c *Cache) MaxStaleMetrics() (evicted int64) {
+	return c.counters.maxStaleEvicted.Load()
+}
+
+// ClimberMetrics returns the number of periods which made the W-TinyLFU window bigger or smaller
+// and the number of climb restarts (eviction.adaptive_window).
+func (c *Cache) ClimberMetrics() (grown, shrunk, restarts int64) {
+	if c.climber == nil {
+		return 0, 0, 0
+	}
+	return c.climber.grown.Load(), c.climber.shrunk.Load(), c.climber.restarts.Load()
+}
+
+// WindowSplit returns the share of entries kept in the W-TinyLFU window (the rest is the main space)
+// and the hit rate of the last period sampled by the window climber (0 unless eviction.adaptive_window).
+func (c *Cache) WindowSplit() (window, sampledHitRate float64) {
+	if c.climber != nil {
+		sampledHitRate = math.Float64frombits(c.climber.hitRate.Load())
+	}
+	return c.db.WindowFraction(), sampledHitRate
+}
+
+// RefreshFailures returns the number of consecutive failed refreshes of the key.
+func (c *Cache) RefreshFailures(key string) (failures int32, ok bool) {
+	k := model.KeyOfString(key)
+	if entry, found := c.peekValue(&k); found {
+		return entry.Failures(), true
+	}
+	return 0, false
+}
+
 func (c *Cache) Around(ctx context.Context, fn func(item pubmodel.CacheItem) bool, rw bool) {
 	c.db.WalkShardsConcurrent(ctx, runtime.GOMAXPROCS(0), func(key uint64, shard *db.Shard) {
 		shard.Walk(ctx, fn, rw)
@@ -120,30 +396,329 @@ func (c *Cache) PeekExpiredTTL() (*model.Entry, bool) {
 	return c.db.PeekExpiredTTL()
 }
 
+// OnTTL executes the TTL callback of an expired entry: removes it or refreshes its payload.
+// An entry beyond lifetime.max_stale is evicted instead of being refreshed.
+// A refresh shares the in-flight slot of the key with concurrent misses.
+func (c *Cache) OnTTL(ctx context.Context, entry *model.Entry) error {
+	if entry.IsRemoveByTTL() {
+		if entry.IsSliding() && entry.Staleness() <= 0 {
+			return nil // accessed after being picked by the lifetimer
+		}
+		_, err := entry.OnTTLCtx(ctx)
+		return err
+	}
+	if entry.IsKeptStale() {
+		return nil // served as is until evicted
+	}
+	if entry.IsBeyondMaxStale(c.cfg) {
+		if _, removed := c.db.RemoveValue(entry.Key().Value(), entry); removed {
+			c.counters.maxStaleEvicted.Add(1)
+		}
+		return nil
+	}
+	if entry.IsBackingOff() {
+		return nil // picked again right after a failed refresh (e.g. queued twice by the lifetimer)
+	}
+	_, shared, err := c.flight.Do(ctx, entry.Key(), func() ([]byte, error) { return c.refresh(ctx, entry) })
+	if shared {
+		c.counters.coalesced.Add(1)
+	}
+	return err
+}
+
 /**
  * Private API.
  */
 
-func (c *Cache) get(key uint64) (*model.Entry, bool) {
-	if ptr, found := c.db.Get(key); found {
+// get looks the key up and touches the found entry; an entry expired in remove mode is removed instead
+// (before the touch, which would renew a sliding TTL).
+func (c *Cache) get(k *pubmodel.Key) (*model.Entry, bool) {
+	if ptr, found := c.db.GetKey(k); found && !c.expireOnRead(ptr) {
 		return c.touch(ptr), true
 	}
 	return nil, false
 }
 
+// loadShared loads a missed key; concurrent misses of the same key (and a background refresh of it)
+// share one loader call.
+func (c *Cache) loadShared(
+	ctx context.Context,
+	k *pubmodel.Key,
+	raw []byte,
+	callback model.TTLCallbackCtx,
+) ([]byte, error) {
+	for {
+		payload, shared, err := c.flight.Do(ctx, k, func() ([]byte, error) { return c.load(ctx, k, raw, callback) })
+		if shared {
+			if isContextErr(err) && ctx.Err() == nil {
+				// the joined call was interrupted by its owner's ctx while ours is alive: load on our own
+				continue
+			}
+			c.counters.coalesced.Add(1)
+		}
+		return payload, err
+	}
+}
+
+// load computes a missed entry by the callback and publishes it.
+func (c *Cache) load(ctx context.Context, k *pubmodel.Key, raw []byte, callback model.TTLCallbackCtx) ([]byte, error) {
+	entry := c.newEntry(k, raw, c.cfgTTLNanoseconds(), c.cfgTTLModeIsRemoveOnTTL())
+
+	// compute response
+	payload, err := callback(ctx, entry)
+	if err != nil {
+		c.storeNegative(k, raw, err)
+		return payload, err
+	}
+	entry.SetPayload(payload)
+
+	// this value could be changed in callback; so set after exec. of callback(entry)
+	if entry.IsRemoveByTTL() {
+		entry.SetCallback(c.removeCallback)
+	} else {
+		entry.SetCallbackCtx(callback)
+	}
+
+	// publish entry to common access; after this moment all accesses should be concurrent safe
+	c.set(entry)
+
+	return payload, nil
+}
+
+// refresh recomputes the payload of a published entry by its callback and stores it.
+func (c *Cache) refresh(ctx context.Context, entry *model.Entry) ([]byte, error) {
+	payload, err := entry.OnTTLCtx(ctx)
+	if err != nil {
+		c.onRefreshFailed(entry, err)
+		return payload, err
+	}
+	weightDiff := entry.RefreshPayload(payload)
+	if cur, found := c.db.GetKey(entry.Key()); found && cur == entry {
+		c.db.AddMem(entry.Key().Value(), weightDiff)
+		c.repin(entry, false)
+	}
+	return payload, nil
+}
+
+// lookup looks the key up and touches the found entry. A found entry which must not be served is a miss.
+func (c *Cache) lookup(k *pubmodel.Key) (*model.Entry, bool) {
+	if entry, found := c.get(k); found && c.isServable(entry) {
+		c.sampleRequest(true)
+		return entry, true
+	}
+	c.sampleRequest(false)
+	return nil, false
+}
+
+// sampleRequest feeds the window climber (eviction.adaptive_window) with the outcome of a request.
+func (c *Cache) sampleRequest(hit bool) {
+	if c.climber != nil {
+		c.climber.record(hit)
+	}
+}
+
+// expireOnRead removes an entry which is removed on TTL once its TTL has elapsed, so a read never serves it
+// even if the lifetimer falls behind. Reports whether the entry is expired (and must be reloaded).
+func (c *Cache) expireOnRead(entry *model.Entry) bool {
+	if !entry.IsRemoveByTTL() || entry.IsNegative() || entry.Staleness() <= 0 {
+		return false
+	}
+	if _, removed := c.db.RemoveValue(entry.Key().Value(), entry); removed {
+		c.counters.lazyExpired.Add(1)
+	}
+	return true
+}
+
+// isServable reports whether a found entry may be served: an expired tombstone, an entry stale
+// beyond the stale window, an entry failing refreshes beyond the grace period and an entry
+// beyond lifetime.max_stale must be reloaded.
+func (c *Cache) isServable(entry *model.Entry) bool {
+	return !entry.IsTombstoneExpired() && !c.isTooStale(entry) && !c.isGraceExpired(entry) &&
+		!entry.IsBeyondMaxStale(c.cfg)
+}
+
+// hit returns the payload of a found entry or the error replayed by a tombstone.
+func (c *Cache) hit(entry *model.Entry) ([]byte, error) {
+	if entry.IsNegative() {
+		c.counters.negativeHits.Add(1)
+		return nil, entry.Err()
+	}
+	return entry.PayloadBytes(), nil
+}
+
+// miss takes the key by value, so it escapes to the heap on the miss path only.
+func (c *Cache) miss(ctx context.Context, k pubmodel.Key, raw []byte, callback model.TTLCallbackCtx) ([]byte, error) {
+	return c.loadShared(ctx, &k, raw, callback)
+}
+
+// peek looks the key up without touching the entry.
+func (c *Cache) peek(k *pubmodel.Key) (*model.Entry, bool) {
+	if entry, found := c.db.GetKey(k); found {
+		return entry, true
+	}
+	return nil, false
+}
+
+// peekValue is peek skipping tombstones (a cached error is not a value), expired entries (see expireOnRead)
+// and entries a read may not serve (see isServable).
+func (c *Cache) peekValue(k *pubmodel.Key) (*model.Entry, bool) {
+	if entry, found := c.peek(k); found && !entry.IsNegative() && !c.expireOnRead(entry) && c.isServable(entry) {
+		return entry, true
+	}
+	return nil, false
+}
+
+func (c *Cache) del(k *pubmodel.Key) bool {
+	c.db.RemoveKey(k)
+	return true
+}
+
 func (c *Cache) set(new *model.Entry) (persisted bool) {
 	key := new.Key().Value()
 	c.admitter.Record(key)
 
-	if old, found := c.db.Get(key); found {
+	if old, found := c.db.GetKey(new.Key()); found {
+		if isReplacedWhole(old, new) {
+			return c.replace(old, new)
+		}
 		if old.IsTheSamePayload(new) {
-			c.touch(old)
+			c.renew(old, new)
 		} else {
 			c.update(old, new)
 		}
 		return true
 	}
 
+	if !c.admit(key) {
+		return false
+	}
+
+	c.db.Set(key, new)
+
+	return true
+}
+
+// replace swaps an entry as a whole (see isReplacedWhole).
+// A tombstone never overwrites a value of the same key stored concurrently with the failed load.
+func (c *Cache) replace(old, new *model.Entry) (persisted bool) {
+	if !isReplaceable(old, new) {
+		return false
+	}
+	c.db.Set(new.Key().Value(), new)
+	return true
+}
+
+// isReplaceable reports whether new may replace old as a whole (see replace).
+func isReplaceable(old, new *model.Entry) bool {
+	return !new.IsNegative() || old.IsNegative()
+}
+
+// storeNegative caches the loader error of the key as a tombstone if negative caching is enabled and the error matches.
+func (c *Cache) storeNegative(k *pubmodel.Key, raw []byte, err error) {
+	if !c.cfg.Negative.Enabled() || !c.cfg.Negative.IsCacheable(err) {
+		return
+	}
+	entry := c.newEntry(k, raw, c.cfg.Negative.TTL.Nanoseconds(), true)
+	entry.SetPayload(nil)
+	entry.SetNegative(err)
+	entry.SetCallback(c.removeCallback)
+	if c.set(entry) {
+		c.counters.negativeStored.Add(1)
+	}
+}
+
+// add is the insert-only counterpart of set: an existing key is never overwritten.
+func (c *Cache) add(new *model.Entry) (persisted bool) {
+	key := new.Key().Value()
+	c.admitter.Record(key)
+
+	if _, found := c.db.GetKey(new.Key()); found {
+		return false
+	}
+
+	if !c.admit(key) {
+		return false
+	}
+
+	return c.db.SetIfAbsent(key, new)
+}
+
+// compute implements Compute. Admission of an absent key runs before the shard is locked,
+// since picking a victim locks other shards.
+func (c *Cache) compute(
+	k *pubmodel.Key,
+	raw []byte,
+	fn func(old []byte, exists bool) (new []byte, op pubmodel.Op),
+) (value []byte, ok bool) {
+	key := k.Value()
+	c.admitter.Record(key)
+
+	_, found := c.db.GetKey(k)
+	admitted := found || c.admit(key)
+
+	var result *model.Entry
+	c.db.Compute(k, func(cur *model.Entry) *model.Entry {
+		exists := cur != nil && !cur.IsNegative()
+		var old []byte
+		if exists {
+			old = cur.PayloadBytes()
+		}
+
+		payload, op := fn(old, exists)
+		switch {
+		case op == pubmodel.OpDelete:
+			return nil
+		case op == pubmodel.OpReplace && exists:
+			c.db.AddMem(key, cur.ReplacePayload(payload))
+			result = cur
+		case op == pubmodel.OpReplace && admitted:
+			result = c.newWrittenEntry(k, raw, payload, nil) // inserted or replaces a tombstone
+		case exists:
+			result = cur
+		default:
+			return cur
+		}
+		value, ok = result.PayloadBytes(), true
+		return result
+	})
+	if result != nil {
+		c.markTouched(result) // moved in LRU by the shard
+	}
+	return value, ok
+}
+
+// compareAndSet implements CompareAndSet: an existing entry is updated in place (like set does),
+// an absent one (or a tombstone) is replaced by new.
+func (c *Cache) compareAndSet(new *model.Entry, expected uint64) (stored bool) {
+	k := new.Key()
+	key := k.Value()
+	c.admitter.Record(key)
+
+	if expected == 0 {
+		if cur, found := c.db.GetKey(k); versionOf(cur) != 0 || (!found && !c.admit(key)) {
+			return false
+		}
+	}
+
+	c.db.Compute(k, func(cur *model.Entry) *model.Entry {
+		if versionOf(cur) != expected {
+			return cur
+		}
+		stored = true
+		if cur == nil || isReplacedWhole(cur, new) {
+			return new
+		}
+		c.overwrite(cur, new)
+		if new.IsPinned() {
+			cur.SetPinned(true) // charged by the map (see repin)
+		}
+		return cur
+	})
+	return stored
+}
+
+// admit runs admission control for a new key and frees space if the hard limit is overcome.
+func (c *Cache) admit(key uint64) (allowed bool) {
 	if c.isAdmissionControlAllowed() {
 		_, victim, found := c.db.PickVictim(shardsSample, keysSample)
 		if !found || !c.admitter.Allow(key, victim.Key().Value()) {
@@ -162,30 +737,185 @@ func (c *Cache) set(new *model.Entry) (persisted bool) {
 		}
 	}
 
-	c.db.Set(key, new)
-
 	return true
 }
 
+// newEntry builds an entry of the key; its original bytes (raw) are copied to the entry only if db.retain_keys is enabled.
+func (c *Cache) newEntry(k *pubmodel.Key, raw []byte, ttl int64, isRemoveOnTTL bool) *model.Entry {
+	entry := model.NewEntry(k, ttl, isRemoveOnTTL)
+	if isRemoveOnTTL && c.cfg.Lifetime.Enabled() {
+		if c.cfg.Lifetime.IsSliding {
+			entry.SetTTLMode(pubmodel.TTLModeSliding)
+		} else if c.cfg.Lifetime.IsKeptStale {
+			entry.SetTTLMode(pubmodel.TTLModeStale)
+		}
+	}
+	if c.cfg.DB.RetainKeys {
+		entry.SetRawKey(raw)
+	}
+	return entry
+}
+
+// newWrittenEntry builds an entry for the explicit write API. Such entries have no loader,
+// so there is nothing to refresh them from: on TTL they are removed even in the refresh mode
+// (or kept stale in the stale mode), so a read never serves them expired (see expireOnRead).
+func (c *Cache) newWrittenEntry(key *pubmodel.Key, raw, value []byte, opts []pubmodel.Option) *model.Entry {
+	entry := c.newEntry(key, raw, c.cfgTTLNanoseconds(), true)
+	for _, opt := range opts {
+		opt(entry)
+	}
+	entry.SetPayload(value)
+	entry.SetCallback(c.removeCallback)
+	return entry
+}
+
 func (c *Cache) touch(existing *model.Entry) *model.Entry {
-	existing.RenewTouchedAt()
 	// move to front in LRU list
 	c.db.Touch(existing.Key().Value())
+	c.markTouched(existing)
+	return existing
+}
+
+// markTouched is touch without LRU movement (used by batches which move keys in LRU by shards).
+func (c *Cache) markTouched(existing *model.Entry) {
+	existing.RenewTouchedAt()
+	if c.isWindowed() {
+		// hits count to the frequency which decides promotion from the window
+		c.admitter.Record(existing.Key().Value())
+	}
+	if c.isRevalidatedOnRead(existing) {
+		c.revalidate(existing)
+		return
+	}
 	// check the entry exists and expired, if so then push it to the per-shard refresh queue
-	if existing.IsExpired(c.cfg) && existing.EnqueueExpired() {
-		if !c.db.EnqueueExpired(existing.Key().Value()) {
-			existing.DequeueExpired()
+	if existing.IsDueOnTTL(c.cfg) {
+		c.enqueueExpired(existing)
+	}
+}
+
+// enqueueExpired pushes an expired entry to the per-shard refresh queue unless it is queued already.
+func (c *Cache) enqueueExpired(entry *model.Entry) {
+	if entry.EnqueueExpired() && !c.db.EnqueueExpired(entry.Key().Value()) {
+		entry.DequeueExpired()
+	}
+}
+
+// revalidate schedules a background refresh of an entry served stale. The queued flag of the entry
+// guarantees exactly one refresh at a time; a refresh which could not be scheduled or failed resets the flag,
+// so a next stale read retries.
+func (c *Cache) revalidate(entry *model.Entry) {
+	if staleness := entry.Staleness(); staleness <= 0 || staleness > c.cfg.StaleWhileRevalidate.StaleWindow {
+		return
+	}
+	c.counters.staleHits.Add(1)
+	if entry.IsBackingOff() || !entry.EnqueueExpired() {
+		return // a failed refresh is backed off or already being revalidated
+	}
+	scheduled := c.revalidator.Submit(func(ctx context.Context) {
+		ctx, cancel := c.refreshCtx(ctx)
+		defer cancel()
+		if err := c.OnTTL(ctx, entry); err == nil {
+			c.counters.revalidated.Add(1)
 		}
+	})
+	if !scheduled {
+		entry.DequeueExpired()
+		c.counters.revalidationsDropped.Add(1)
 	}
-	return existing
+}
+
+// isRevalidatedOnRead reports whether staleness of the entry is handled on read by stale-while-revalidate:
+// only entries in refresh TTL mode are refreshed, tombstones are not.
+func (c *Cache) isRevalidatedOnRead(entry *model.Entry) bool {
+	return c.revalidator != nil && entry.IsRefreshByTTL() && !entry.IsNegative()
+}
+
+// isTooStale reports whether the entry outlived the stale window, so a read must reload it synchronously.
+func (c *Cache) isTooStale(entry *model.Entry) bool {
+	return c.isRevalidatedOnRead(entry) && entry.Staleness() > c.cfg.StaleWhileRevalidate.StaleWindow
+}
+
+// onRefreshFailed releases the queued flag of the entry, so it can be picked again. With stale-if-error,
+// the next attempt is backed off, and the entry is removed once served stale beyond the grace period;
+// a next read reloads it synchronously.
+func (c *Cache) onRefreshFailed(entry *model.Entry, err error) {
+	entry.DequeueExpired()
+
+	cfg := c.staleIfErrorCfg()
+	if cfg == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	c.counters.refreshFailures.Add(1)
+	entry.OnRefreshFailed(cfg.Backoff, cfg.MaxBackoff)
+
+	if entry.Staleness() > cfg.GracePeriod {
+		if _, removed := c.db.RemoveValue(entry.Key().Value(), entry); removed {
+			c.counters.graceExpired.Add(1)
+		}
+	}
+}
+
+// isGraceExpired reports whether the entry failed refreshes and has been served stale beyond the grace period.
+func (c *Cache) isGraceExpired(entry *model.Entry) bool {
+	cfg := c.staleIfErrorCfg()
+	return cfg != nil && entry.Failures() > 0 && entry.Staleness() > cfg.GracePeriod
+}
+
+func (c *Cache) staleIfErrorCfg() *config.StaleIfErrorCfg {
+	if c.cfg.Lifetime.Enabled() && c.cfg.Lifetime.StaleIfError.Enabled() {
+		return c.cfg.Lifetime.StaleIfError
+	}
+	return nil
+}
+
+// refreshCtx bounds a background refresh by the configured refresh timeout.
+func (c *Cache) refreshCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.cfg.Lifetime.Enabled() && c.cfg.Lifetime.RefreshTimeout > 0 {
+		return context.WithTimeout(ctx, c.cfg.Lifetime.RefreshTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// renew marks an entry written again with the same payload as fresh; the TTL, the priority and the pin
+// of the write apply like in update.
+func (c *Cache) renew(existing, in *model.Entry) {
+	c.markRenewed(existing, in)
+	c.touch(existing)
+	c.repin(existing, in.IsPinned())
+}
+
+// markRenewed is renew without touching and pinning, so it may run under the shard lock.
+func (c *Cache) markRenewed(existing, in *model.Entry) {
+	existing.SetTTL(in.TTL())
+	existing.SetPriority(in.Priority())
+	existing.RenewUpdatedAt()
+	existing.ResetFailures()
+	existing.DequeueExpired()
 }
 
 func (c *Cache) update(existing, in *model.Entry) {
+	c.overwrite(existing, in)
+	c.db.Touch(existing.Key().Value())
+	c.repin(existing, in.IsPinned())
+}
+
+// overwrite is update without LRU movement and pinning (see repin), so it may run under the shard lock.
+func (c *Cache) overwrite(existing, in *model.Entry) {
 	c.db.AddMem(existing.Key().Value(), existing.SwapPayloads(in))
+	existing.SetTTL(in.TTL())
+	existing.SetPriority(in.Priority())
+	existing.ResetFailures()
 	existing.RenewTouchedAt()
 	existing.RenewUpdatedAt()
 	existing.DequeueExpired()
-	c.db.Touch(existing.Key().Value())
+}
+
+// repin pins an existing entry by a write of a pinned item (a write never unpins: pinning belongs to the key)
+// and keeps the pinned budget in sync with the weight of an entry updated in place.
+func (c *Cache) repin(existing *model.Entry, pin bool) {
+	if pin || existing.IsPinned() {
+		c.db.Repin(existing, pin)
+	}
 }
 
 func (c *Cache) cfgTTLNanoseconds() int64 {
@@ -203,11 +933,11 @@ func (c *Cache) cfgTTLModeIsRemoveOnTTL() bool {
 }
 
 func (c *Cache) removeCallback(entry pubmodel.Item) ([]byte, error) {
-	c.db.Remove(entry.Key().Value())
+	c.db.RemoveKey(entry.Key())
 	return nil, nil
 }
 
-func (c *Cache) remove(entry *model.Entry) (int64, bool) { return c.db.Remove(entry.Key().Value()) }
+func (c *Cache) remove(entry *model.Entry) (int64, bool) { return c.db.RemoveKey(entry.Key()) }
 
 func (c *Cache) hardEvictUntilWithinLimit() (freed, evicted int64) {
 	if c.cfg.Eviction.Enabled() {
@@ -216,10 +946,73 @@ func (c *Cache) hardEvictUntilWithinLimit() (freed, evicted int64) {
 	return
 }
 
+// rawOf returns bytes of the key without copying; they must not be modified.
+func rawOf(key string) []byte {
+	return unsafe.Slice(unsafe.StringData(key), len(key))
+}
+
+func wrapCallback(callback func(item pubmodel.Item) ([]byte, error)) model.TTLCallbackCtx {
+	return func(_ context.Context, item pubmodel.Item) ([]byte, error) { return callback(item) }
+}
+
+// withMeta returns the payload of a found entry along with its metadata.
+func (c *Cache) withMeta(entry *model.Entry) ([]byte, pubmodel.Meta, error) {
+	if entry.IsNegative() {
+		data, err := c.hit(entry)
+		return data, nil, err
+	}
+	data, meta := entry.PayloadWithMeta()
+	return data, meta, nil
+}
+
+// versioned returns the payload of a found entry along with its version. The version is read first:
+// a concurrent update makes it older than the payload, so a following CompareAndSet fails
+// instead of overwriting a payload the caller hasn't seen.
+func (c *Cache) versioned(entry *model.Entry) ([]byte, uint64, error) {
+	if entry.IsNegative() {
+		data, err := c.hit(entry)
+		return data, 0, err
+	}
+	version := entry.Version()
+	return entry.PayloadBytes(), version, nil
+}
+
+// isReplacedWhole reports whether new must replace old as a whole entry instead of updating its payload in place:
+// payloads of a tombstone and a value aren't comparable, and tags are indexed by entry.
+func isReplacedWhole(old, new *model.Entry) bool {
+	return old.IsNegative() || new.IsNegative() || !old.IsTheSameTags(new)
+}
+
+// versionOf returns the version of a stored value; an absent key and a tombstone have no version.
+func versionOf(entry *model.Entry) uint64 {
+	if entry == nil || entry.IsNegative() {
+		return 0
+	}
+	return entry.Version()
+}
+
+// stateOf tells whether a served entry is within its TTL.
+func stateOf(entry *model.Entry) pubmodel.State {
+	if entry.Staleness() > 0 {
+		return pubmodel.StateStale
+	}
+	return pubmodel.StateFresh
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
 func (c *Cache) hardMemoryLimitOvercome() bool {
 	return c.cfg.Eviction.Enabled() && c.db.Len() > 0 && c.db.Mem()-c.cfg.DB.SizeBytes > 0
 }
 
+// isAdmissionControlAllowed reports whether new keys are checked by admission control on insert.
+// In W-TinyLFU mode they always enter the window and TinyLFU decides on promotion from it instead.
 func (c *Cache) isAdmissionControlAllowed() bool {
-	return c.cfg.AdmissionControl.Enabled() && c.db.Len() > 0 && c.db.Mem() > 0
+	return c.cfg.AdmissionControl.Enabled() && !c.isWindowed() && c.db.Len() > 0 && c.db.Mem() > 0
+}
+
+func (c *Cache) isWindowed() bool {
+	return c.cfg.Eviction.Enabled() && c.cfg.Eviction.IsWindowed
 }
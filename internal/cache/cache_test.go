@@ -8,6 +8,9 @@ import (
 	pubmodel "github.com/Borislavv/go-ash-cache/model"
 	"github.com/stretchr/testify/require"
 	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -422,3 +425,925 @@ func TestCache_Touch_MovesToFrontAndEnqueuesExpired(t *testing.T) {
 	// Note: exact behavior depends on cachedtime and expiration state
 	require.NotNil(t, touched)
 }
+
+// TestCache_Set_StoresWithoutCallback stores a value that is then served by Get without running the loader.
+func TestCache_Set_StoresWithoutCallback(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	require.True(t, c.Set("test", []byte("data")))
+
+	var callbackCalled bool
+	data, err := c.Get("test", func(item pubmodel.Item) ([]byte, error) {
+		callbackCalled = true
+		return []byte("loaded"), nil
+	})
+
+	require.NoError(t, err)
+	require.False(t, callbackCalled, "callback should not be called for a written key")
+	require.Equal(t, []byte("data"), data)
+
+	// Overwrite
+	require.True(t, c.Set("test", []byte("data2")))
+	data, err = c.Get("test", nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("data2"), data)
+	require.Equal(t, int64(1), c.Len())
+}
+
+// TestCache_SetWithTTL_OverridesTTL applies the given TTL and removes the entry on TTL in refresh mode.
+func TestCache_SetWithTTL_OverridesTTL(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+		Lifetime: &config.LifetimerCfg{
+			OnTTL: config.TTLModeRefresh,
+			TTL:   time.Hour,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	require.True(t, c.SetWithTTL("test", []byte("data"), time.Minute))
+
+	entry, ok := c.db.Get(model.NewKey("test").Value())
+	require.True(t, ok)
+	require.Equal(t, time.Minute, entry.TTL())
+
+	// There is no loader to refresh from, so reaching TTL removes the entry.
+	_, err := entry.OnTTL()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), c.Len())
+}
+
+// TestCache_SetWithTTL_SamePayload_AppliesTTL applies the TTL of a write which doesn't change the payload.
+func TestCache_SetWithTTL_SamePayload_AppliesTTL(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	require.True(t, c.SetWithTTL("test", []byte("data"), time.Hour))
+	require.True(t, c.SetWithTTL("test", []byte("data"), time.Second))
+
+	entry, ok := c.db.Get(model.NewKey("test").Value())
+	require.True(t, ok)
+	require.Equal(t, time.Second, entry.TTL())
+}
+
+// TestCache_Add_DoesNotOverwrite inserts only absent keys.
+func TestCache_Add_DoesNotOverwrite(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	require.True(t, c.Add("test", []byte("data1")))
+	require.False(t, c.Add("test", []byte("data2")), "existing key must not be overwritten")
+
+	data, err := c.Get("test", nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("data1"), data)
+	require.Equal(t, int64(1), c.Len())
+}
+
+// TestCache_Peek_LeavesNoAccessTrace returns cached data without touching, recording or enqueueing the entry.
+func TestCache_Peek_LeavesNoAccessTrace(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+		Lifetime: &config.LifetimerCfg{
+			OnTTL: config.TTLModeRefresh,
+			TTL:   time.Millisecond,
+		},
+		AdmissionControl: &config.AdmissionControlCfg{
+			Capacity:            1000,
+			Shards:              4,
+			MinTableLenPerShard: 64,
+			SampleMultiplier:    10,
+			DoorBitsPerCounter:  2,
+		},
+		Eviction: &config.EvictionCfg{
+			LRUMode: config.LRUModeListing,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	data, ok := c.Peek("test")
+	require.False(t, ok)
+	require.Nil(t, data)
+	require.False(t, c.Has("test"))
+
+	require.True(t, c.Set("test", []byte("data")))
+	entry, _ := c.db.Get(model.NewKey("test").Value())
+	entry.UntouchRefreshedAt()
+	time.Sleep(5 * time.Millisecond)
+
+	touchedAt := entry.TouchedAt()
+	estimate := c.admitter.Estimate(entry.Key().Value())
+
+	data, ok = c.Peek("test")
+	require.True(t, ok)
+	require.Equal(t, []byte("data"), data)
+	require.True(t, c.Has("test"))
+
+	require.Equal(t, touchedAt, entry.TouchedAt(), "peek must not renew touchedAt")
+	require.Equal(t, estimate, c.admitter.Estimate(entry.Key().Value()), "peek must not be recorded")
+	require.True(t, entry.EnqueueExpired(), "peek must not enqueue expired entry")
+}
+
+// TestCache_Get_ConcurrentMisses_SingleLoaderCall runs the callback once for concurrent misses of one key.
+func TestCache_Get_ConcurrentMisses_SingleLoaderCall(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	const callers = 64
+	var (
+		wg      sync.WaitGroup
+		invokes atomic.Int64
+		start   = make(chan struct{})
+	)
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			<-start
+			data, err := c.Get("test", func(item pubmodel.Item) ([]byte, error) {
+				invokes.Add(1)
+				time.Sleep(50 * time.Millisecond)
+				return []byte("data"), nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, []byte("data"), data)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	require.Equal(t, int64(1), invokes.Load())
+	require.Equal(t, int64(callers-1), c.FlightMetrics())
+	require.Equal(t, int64(1), c.Len())
+}
+
+// TestCache_OnTTL_RefreshStoresPayload stores the refreshed payload and keeps memory accounting consistent.
+func TestCache_OnTTL_RefreshStoresPayload(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+		Lifetime: &config.LifetimerCfg{
+			OnTTL: config.TTLModeRefresh,
+			TTL:   time.Hour,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	var payload = make([]byte, 16)
+	_, err := c.Get("test", func(item pubmodel.Item) ([]byte, error) {
+		return payload, nil
+	})
+	require.NoError(t, err)
+
+	entry, ok := c.db.Get(model.NewKey("test").Value())
+	require.True(t, ok)
+
+	payload = make([]byte, 1024)
+	require.NoError(t, c.OnTTL(ctx, entry))
+
+	require.Len(t, entry.PayloadBytes(), 1024)
+	require.Equal(t, entry.Weight(), c.Mem())
+}
+
+// TestCache_GetCtx_PassesContext passes the caller's ctx to the callback and keeps the callback for refreshes.
+func TestCache_GetCtx_PassesContext(t *testing.T) {
+	type ctxKey struct{}
+
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+		Lifetime: &config.LifetimerCfg{
+			OnTTL: config.TTLModeRefresh,
+			TTL:   time.Hour,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(context.Background(), cfg, slog.Default())
+
+	var seen any
+	callback := func(ctx context.Context, item pubmodel.Item) ([]byte, error) {
+		seen = ctx.Value(ctxKey{})
+		return []byte("data"), nil
+	}
+
+	data, err := c.GetCtx(context.WithValue(context.Background(), ctxKey{}, "get"), "test", callback)
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), data)
+	require.Equal(t, "get", seen)
+
+	entry, ok := c.db.Get(model.NewKey("test").Value())
+	require.True(t, ok)
+	require.NoError(t, c.OnTTL(context.WithValue(context.Background(), ctxKey{}, "refresh"), entry))
+	require.Equal(t, "refresh", seen)
+}
+
+// TestCache_GetCtx_WaiterRetriesInterruptedCall loads on its own when the joined call was canceled by its owner.
+func TestCache_GetCtx_WaiterRetriesInterruptedCall(t *testing.T) {
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(context.Background(), cfg, slog.Default())
+
+	ownerCtx, cancelOwner := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	go func() {
+		_, _ = c.GetCtx(ownerCtx, "test", func(ctx context.Context, item pubmodel.Item) ([]byte, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	}()
+	<-started
+
+	time.AfterFunc(20*time.Millisecond, cancelOwner)
+
+	data, err := c.GetCtx(context.Background(), "test", func(ctx context.Context, item pubmodel.Item) ([]byte, error) {
+		return []byte("data"), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), data)
+}
+
+// TestCache_GetMany_LoadsMissesInOneCall returns results in input order and loads deduplicated misses at once.
+func TestCache_GetMany_LoadsMissesInOneCall(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+	c.Set("a", []byte("cached-a"))
+
+	failure := errors.New("no such key")
+	var calls [][]string
+	loader := func(keys []string, items []pubmodel.Item) ([][]byte, []error) {
+		calls = append(calls, keys)
+		payloads, errs := make([][]byte, len(keys)), make([]error, len(keys))
+		for i, key := range keys {
+			if key == "missing" {
+				errs[i] = failure
+				continue
+			}
+			payloads[i] = []byte("loaded-" + key)
+		}
+		return payloads, errs
+	}
+
+	data, errs := c.GetMany([]string{"b", "a", "missing", "b", "c"}, loader)
+	require.Equal(t, [][]string{{"b", "missing", "c"}}, calls)
+	require.Equal(t, [][]byte{[]byte("loaded-b"), []byte("cached-a"), nil, []byte("loaded-b"), []byte("loaded-c")}, data)
+	require.Equal(t, []error{nil, nil, failure, nil, nil}, errs)
+	require.Equal(t, int64(3), c.Len())
+
+	// the second call hits everything but the failed key
+	_, errs = c.GetMany([]string{"a", "b", "c", "missing"}, loader)
+	require.Equal(t, []string{"missing"}, calls[1])
+	require.ErrorIs(t, errs[3], failure)
+
+	// a loader returning a wrong number of results fails every missed key
+	_, errs = c.GetMany([]string{"x", "a"}, func(keys []string, items []pubmodel.Item) ([][]byte, []error) {
+		return nil, nil
+	})
+	require.Equal(t, []error{ErrBatchLoaderMismatch, nil}, errs)
+}
+
+// TestCache_SetMany_DelMany stores and removes a batch of keys.
+func TestCache_SetMany_DelMany(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	keys := []string{"k1", "k2", "k3"}
+	stored := c.SetMany(keys, [][]byte{[]byte("v1"), []byte("v2"), []byte("v3")}, pubmodel.WithTTL(time.Hour))
+	require.Equal(t, []bool{true, true, true}, stored)
+	require.Equal(t, int64(3), c.Len())
+
+	// overwrite of an existing key updates it in place
+	c.SetMany([]string{"k2"}, [][]byte{[]byte("v2-new")})
+	data, ok := c.Peek("k2")
+	require.True(t, ok)
+	require.Equal(t, []byte("v2-new"), data)
+	require.Equal(t, int64(3), c.Len())
+
+	// a batch of overwrites renews, updates and inserts keys at once
+	stored = c.SetMany([]string{"k1", "k2", "k4"}, [][]byte{[]byte("v1"), []byte("v2-next"), []byte("v4")},
+		pubmodel.WithPriority(pubmodel.PriorityHigh), pubmodel.WithPin())
+	require.Equal(t, []bool{true, true, true}, stored)
+	require.Equal(t, int64(4), c.Len())
+	var pinned int64
+	for _, key := range []string{"k1", "k2", "k4"} {
+		entry, _ := c.peek(model.NewKey(key))
+		require.Equal(t, pubmodel.PriorityHigh, entry.Priority())
+		require.True(t, entry.IsPinned())
+		pinned += entry.Weight()
+	}
+	require.Equal(t, pinned, c.PinnedMem())
+	data, _ = c.Peek("k2")
+	require.Equal(t, []byte("v2-next"), data)
+	require.True(t, c.Del("k4"))
+
+	require.Equal(t, int64(2), c.DelMany([]string{"k1", "k3", "absent"}))
+	require.Equal(t, int64(1), c.Len())
+	require.False(t, c.Has("k1"))
+	require.True(t, c.Has("k2"))
+}
+
+// TestCache_Negative_ReplaysMatchingErrors caches matching loader errors as tombstones and replays them.
+func TestCache_Negative_ReplaysMatchingErrors(t *testing.T) {
+	ctx := context.Background()
+	notFound, unavailable := errors.New("not found"), errors.New("unavailable")
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+		Negative: &config.NegativeCfg{
+			Errors: []error{notFound},
+		},
+	}
+	cfg.AdjustConfig()
+	require.Equal(t, config.DefaultNegativeTTL, cfg.Negative.TTL)
+
+	c := New(ctx, cfg, slog.Default())
+
+	var calls int
+	failWith := func(err error) func(item pubmodel.Item) ([]byte, error) {
+		return func(item pubmodel.Item) ([]byte, error) {
+			calls++
+			return nil, err
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := c.Get("missing", failWith(notFound))
+		require.ErrorIs(t, err, notFound)
+	}
+	require.Equal(t, 1, calls, "tombstone must be replayed instead of calling the loader")
+	require.Equal(t, int64(1), c.Len())
+	require.Greater(t, c.Mem(), int64(0), "tombstone must count toward memory")
+
+	// a tombstone is not a value
+	require.False(t, c.Has("missing"))
+	_, ok := c.Peek("missing")
+	require.False(t, ok)
+
+	hits, stored := c.NegativeMetrics()
+	require.Equal(t, int64(2), hits)
+	require.Equal(t, int64(1), stored)
+
+	// non-matching errors are not cached
+	for i := 0; i < 2; i++ {
+		_, err := c.Get("broken", failWith(unavailable))
+		require.ErrorIs(t, err, unavailable)
+	}
+	require.Equal(t, 3, calls)
+
+	// an explicit write replaces the tombstone
+	require.True(t, c.Set("missing", []byte("found")))
+	data, err := c.Get("missing", failWith(notFound))
+	require.NoError(t, err)
+	require.Equal(t, []byte("found"), data)
+	require.Equal(t, int64(1), c.Len())
+}
+
+// TestCache_Collisions_KeepBothKeys stores keys with the same 64-bit hash side by side.
+func TestCache_Collisions_KeepBothKeys(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	// same hash, different full keys
+	key1, key2 := pubmodel.NewKey(42, 1, 1), pubmodel.NewKey(42, 2, 2)
+	entry1 := model.NewEntry(key1, 0, false)
+	entry1.SetPayload([]byte("data1"))
+	entry2 := model.NewEntry(key2, 0, false)
+	entry2.SetPayload([]byte("data2"))
+
+	require.True(t, c.set(entry1))
+	require.True(t, c.set(entry2))
+	require.Equal(t, int64(2), c.Len())
+	require.Equal(t, entry1.Weight()+entry2.Weight(), c.Mem())
+	require.Equal(t, int64(1), c.CollisionMetrics())
+
+	found, ok := c.peekValue(key1)
+	require.True(t, ok)
+	require.Equal(t, []byte("data1"), found.PayloadBytes())
+	found, ok = c.peekValue(key2)
+	require.True(t, ok)
+	require.Equal(t, []byte("data2"), found.PayloadBytes())
+
+	// removing one key keeps the other
+	_, hit := c.remove(entry1)
+	require.True(t, hit)
+	_, ok = c.peekValue(key1)
+	require.False(t, ok)
+	_, ok = c.peekValue(key2)
+	require.True(t, ok)
+	require.Equal(t, int64(1), c.Len())
+	require.Equal(t, entry2.Weight(), c.Mem())
+}
+
+// TestCache_Compute_KeepReplaceDelete applies compute ops atomically and keeps memory accounting correct.
+func TestCache_Compute_KeepReplaceDelete(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	// keep of an absent key inserts nothing
+	_, ok := c.Compute("counter", func(old []byte, exists bool) ([]byte, pubmodel.Op) {
+		require.False(t, exists)
+		return nil, pubmodel.OpKeep
+	})
+	require.False(t, ok)
+	require.Equal(t, int64(0), c.Len())
+
+	increment := func(old []byte, exists bool) ([]byte, pubmodel.Op) {
+		var n byte
+		if exists {
+			n = old[0]
+		}
+		return []byte{n + 1}, pubmodel.OpReplace
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Compute("counter", increment)
+		}()
+	}
+	wg.Wait()
+
+	data, ok := c.Peek("counter")
+	require.True(t, ok)
+	require.Equal(t, []byte{100}, data, "no concurrent update may be lost")
+	require.Equal(t, int64(1), c.Len())
+
+	entry, _ := c.peek(model.NewKey("counter"))
+	require.Equal(t, entry.Weight(), c.Mem())
+
+	// replacing with a bigger payload is accounted
+	value, ok := c.Compute("counter", func(old []byte, exists bool) ([]byte, pubmodel.Op) {
+		return make([]byte, 1024), pubmodel.OpReplace
+	})
+	require.True(t, ok)
+	require.Len(t, value, 1024)
+	require.Equal(t, entry.Weight(), c.Mem())
+
+	value, ok = c.Compute("counter", func(old []byte, exists bool) ([]byte, pubmodel.Op) {
+		return nil, pubmodel.OpKeep
+	})
+	require.True(t, ok)
+	require.Len(t, value, 1024)
+
+	_, ok = c.Compute("counter", func(old []byte, exists bool) ([]byte, pubmodel.Op) {
+		return nil, pubmodel.OpDelete
+	})
+	require.False(t, ok)
+	require.Equal(t, int64(0), c.Len())
+	require.Equal(t, int64(0), c.Mem())
+}
+
+// TestCache_CompareAndSet_FailsOnChangedVersion rejects writes and deletes based on an outdated version.
+func TestCache_CompareAndSet_FailsOnChangedVersion(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	load := func(item pubmodel.Item) ([]byte, error) { return []byte("v1"), nil }
+
+	data, v1, err := c.GetWithVersion("key", load)
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), data)
+	require.NotZero(t, v1)
+
+	// a hit returns the same version
+	_, version, err := c.GetWithVersion("key", load)
+	require.NoError(t, err)
+	require.Equal(t, v1, version)
+
+	// 0 expects the key to be absent
+	require.False(t, c.CompareAndSet("key", 0, []byte("other")))
+
+	require.True(t, c.CompareAndSet("key", v1, []byte("v2")))
+	data, v2, _ := c.GetWithVersion("key", load)
+	require.Equal(t, []byte("v2"), data)
+	require.Greater(t, v2, v1)
+
+	// the entry changed in between
+	require.False(t, c.CompareAndSet("key", v1, []byte("lost")))
+	require.False(t, c.CompareAndDelete("key", v1))
+	data, _ = c.Peek("key")
+	require.Equal(t, []byte("v2"), data)
+
+	require.True(t, c.CompareAndDelete("key", v2))
+	require.False(t, c.Has("key"))
+	require.Equal(t, int64(0), c.Mem())
+
+	require.True(t, c.CompareAndSet("key", 0, []byte("v3")))
+	_, v3, _ := c.GetWithVersion("key", load)
+	require.Greater(t, v3, v2, "a key stored again never gets an old version")
+	require.Equal(t, int64(1), c.Len())
+}
+
+// TestCache_InvalidateTag_RemovesTaggedEntries drops every entry tagged by a loader or an option.
+func TestCache_InvalidateTag_RemovesTaggedEntries(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	for _, page := range []string{"page:1", "page:2"} {
+		_, err := c.Get(page, func(item pubmodel.Item) ([]byte, error) {
+			item.AddTags("product:1", "category:1")
+			return []byte(page), nil
+		})
+		require.NoError(t, err)
+	}
+	require.True(t, c.Set("page:3", []byte("page:3"), pubmodel.WithTags("category:1")))
+	require.True(t, c.Set("page:4", []byte("page:4")))
+	untagged, _ := c.peek(model.NewKey("page:4"))
+
+	require.Equal(t, int64(2), c.InvalidateTag("product:1"))
+	require.False(t, c.Has("page:1"))
+	require.False(t, c.Has("page:2"))
+	require.True(t, c.Has("page:3"))
+
+	require.Equal(t, int64(1), c.InvalidateTags("category:1", "absent"))
+	require.Equal(t, int64(1), c.Len())
+	require.Equal(t, untagged.Weight(), c.Mem())
+
+	// a write with other tags retags the key
+	require.True(t, c.Set("page:4", []byte("page:4"), pubmodel.WithTags("product:2")))
+	require.Equal(t, int64(1), c.InvalidateTag("product:2"))
+	require.Equal(t, int64(0), c.Mem())
+}
+
+// TestCache_Scan_DelPrefix lists retained keys page by page and removes them by prefix.
+func TestCache_Scan_DelPrefix(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes:  10 * 1024 * 1024,
+			RetainKeys: true,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	for i := 0; i < 50; i++ {
+		require.True(t, c.Set("user:"+strconv.Itoa(i), []byte("v")))
+	}
+	_, err := c.Get("session:1", func(item pubmodel.Item) ([]byte, error) { return []byte("v"), nil })
+	require.NoError(t, err)
+
+	var (
+		pages  int
+		cursor uint64
+		seen   = make(map[string]struct{})
+	)
+	for {
+		keys, next, err := c.Scan(cursor, "user:*", 5)
+		require.NoError(t, err)
+		for _, key := range keys {
+			seen[key] = struct{}{}
+		}
+		pages++
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	require.Len(t, seen, 50)
+	require.Greater(t, pages, 1)
+
+	removed, err := c.DelPrefix("user:")
+	require.NoError(t, err)
+	require.Equal(t, int64(50), removed)
+	require.Equal(t, int64(1), c.Len())
+	require.True(t, c.Has("session:1"))
+
+	entry, _ := c.peek(model.NewKey("session:1"))
+	require.Equal(t, []byte("session:1"), entry.RawKey())
+	require.Equal(t, entry.Weight(), c.Mem(), "retained key is counted in the weight")
+}
+
+// TestCache_Scan_RequiresRetainedKeys refuses to scan or remove by prefix without original keys.
+func TestCache_Scan_RequiresRetainedKeys(t *testing.T) {
+	cfg := &config.Cache{DB: config.DBCfg{SizeBytes: 10 * 1024 * 1024}}
+	cfg.AdjustConfig()
+
+	c := New(context.Background(), cfg, slog.Default())
+	require.True(t, c.Set("user:1", []byte("v")))
+
+	_, _, err := c.Scan(0, "", 10)
+	require.ErrorIs(t, err, ErrKeysNotRetained)
+	_, err = c.DelPrefix("user:")
+	require.ErrorIs(t, err, ErrKeysNotRetained)
+	require.True(t, c.Has("user:1"))
+}
+
+// TestMatchGlob matches Scan patterns byte by byte.
+func TestMatchGlob(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, key string
+		match        bool
+	}{
+		{"user:*", "user:123", true},
+		{"user:*", "user:", true},
+		{"user:*", "users:1", false},
+		{"*:profile", "user:1:profile", true},
+		{"user:?", "user:1", true},
+		{"user:?", "user:12", false},
+		{"*a*b", "xaxxb", true},
+		{"*a*b", "xaxxc", false},
+		{"exact", "exact", true},
+		{"*", "", true},
+	} {
+		require.Equal(t, tc.match, matchGlob(tc.pattern, []byte(tc.key)), "%q ~ %q", tc.pattern, tc.key)
+	}
+}
+
+// TestCache_All_YieldsWithoutHoldingLocks iterates over items, writes from the loop body and stops early.
+func TestCache_All_YieldsWithoutHoldingLocks(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+	for i := 0; i < 100; i++ {
+		require.True(t, c.Set("key:"+strconv.Itoa(i), []byte(strconv.Itoa(i)), pubmodel.WithTags(strconv.Itoa(i%2))))
+	}
+
+	var even int
+	for _, item := range c.AllFunc(func(_ pubmodel.Key, item pubmodel.ItemView) bool { return item.Tags[0] == "0" }) {
+		require.Equal(t, []string{"0"}, item.Tags)
+		if even++; even == 10 {
+			break
+		}
+	}
+	require.Equal(t, 10, even)
+
+	var seen int
+	for key, item := range c.All() {
+		entry, found := c.peek(&key)
+		require.True(t, found)
+		require.Equal(t, entry.PayloadBytes(), item.Payload)
+		require.Equal(t, entry.Version(), item.Version)
+		// the shard of the key is not locked by the iterator
+		_, removed := c.db.RemoveKey(&key)
+		require.True(t, removed)
+		seen++
+	}
+	require.Equal(t, 100, seen)
+	require.Equal(t, int64(0), c.Len())
+}
+
+// TestCache_GetWithMeta_StoresLoaderMeta returns metadata set by the loader and replaces it on refresh.
+func TestCache_GetWithMeta_StoresLoaderMeta(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+		Lifetime: &config.LifetimerCfg{
+			OnTTL: config.TTLModeRefresh,
+			TTL:   time.Hour,
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(ctx, cfg, slog.Default())
+
+	var etag int
+	load := func(item pubmodel.Item) ([]byte, error) {
+		etag++
+		item.SetMeta("Content-Type", "text/html")
+		item.SetMeta("ETag", strconv.Itoa(etag))
+		return []byte("<html/>"), nil
+	}
+
+	data, meta, err := c.GetWithMeta("page", load)
+	require.NoError(t, err)
+	require.Equal(t, []byte("<html/>"), data)
+	require.Equal(t, pubmodel.Meta{"Content-Type": "text/html", "ETag": "1"}, meta)
+
+	_, meta, err = c.GetWithMeta("page", load)
+	require.NoError(t, err)
+	require.Equal(t, "1", meta["ETag"], "a hit doesn't call the loader")
+
+	// a refresh swaps metadata along with the payload
+	entry, _ := c.peek(model.NewKey("page"))
+	require.NoError(t, c.OnTTL(ctx, entry))
+	_, meta, _ = c.GetWithMeta("page", load)
+	require.Equal(t, "2", meta["ETag"])
+	require.Equal(t, entry.Weight(), c.Mem())
+
+	// a write with other metadata replaces it
+	require.True(t, c.Set("page", []byte("<html/>"), pubmodel.WithMeta("ETag", "3")))
+	_, meta, _ = c.GetWithMeta("page", load)
+	require.Equal(t, pubmodel.Meta{"ETag": "3"}, meta)
+	require.Equal(t, entry.Weight(), c.Mem())
+}
+
+// TestCache_Pin_CappedByPinnedFraction pins keys within db.pinned_fraction of the cache size.
+func TestCache_Pin_CappedByPinnedFraction(t *testing.T) {
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes:      10 * 1024 * 1024,
+			PinnedFraction: 0.1, // 1MiB
+		},
+	}
+	cfg.AdjustConfig()
+
+	c := New(context.Background(), cfg, slog.Default())
+	value := make([]byte, 600*1024)
+
+	// pinned by a loader
+	_, err := c.Get("flags", func(item pubmodel.Item) ([]byte, error) {
+		item.Pin()
+		return value, nil
+	})
+	require.NoError(t, err)
+	require.Positive(t, c.PinnedMem())
+
+	// pinned by an option over the limit is stored unpinned
+	require.True(t, c.Set("config", value, pubmodel.WithPin()))
+	entry, _ := c.peek(model.NewKey("config"))
+	require.False(t, entry.IsPinned())
+	require.False(t, c.Pin("config"), "over the limit")
+	require.False(t, c.Pin("absent"))
+
+	require.True(t, c.Unpin("flags"))
+	require.False(t, c.Unpin("flags"), "not pinned anymore")
+	require.Zero(t, c.PinnedMem())
+
+	require.True(t, c.Pin("config"))
+	require.Equal(t, entry.Weight(), c.PinnedMem())
+
+	// pinning belongs to the key: a replacement keeps it
+	require.True(t, c.Set("config", []byte("v2"), pubmodel.WithTags("other")))
+	replaced, _ := c.peek(model.NewKey("config"))
+	require.NotSame(t, entry, replaced)
+	require.True(t, replaced.IsPinned())
+	require.Equal(t, replaced.Weight(), c.PinnedMem())
+}
+
+// TestCache_Set_CarriesPriorityAndPin applies the priority and the pin of a write to the stored key.
+func TestCache_Set_CarriesPriorityAndPin(t *testing.T) {
+	cfg := &config.Cache{DB: config.DBCfg{SizeBytes: 10 * 1024 * 1024}}
+	cfg.AdjustConfig()
+	c := New(context.Background(), cfg, slog.Default())
+
+	require.True(t, c.Set("key", []byte("v1")))
+	entry, _ := c.peek(model.NewKey("key"))
+	require.False(t, entry.IsPinned())
+
+	// updated in place
+	require.True(t, c.Set("key", []byte("v2"), pubmodel.WithPriority(pubmodel.PriorityHigh), pubmodel.WithPin()))
+	require.Equal(t, pubmodel.PriorityHigh, entry.Priority())
+	require.True(t, entry.IsPinned())
+	require.Equal(t, entry.Weight(), c.PinnedMem())
+
+	// renewed with the same payload; a write never unpins
+	require.True(t, c.Set("key", []byte("v2"), pubmodel.WithPriority(pubmodel.PriorityLow)))
+	require.Equal(t, pubmodel.PriorityLow, entry.Priority())
+	require.True(t, entry.IsPinned())
+
+	// a pinned payload growing in place is recharged
+	require.True(t, c.Set("key", make([]byte, 4096)))
+	require.Equal(t, entry.Weight(), c.PinnedMem())
+	require.True(t, c.CompareAndSet("key", entry.Version(), []byte("small")))
+	require.Equal(t, entry.Weight(), c.PinnedMem())
+
+	require.True(t, c.Unpin("key"))
+	require.Zero(t, c.PinnedMem())
+	require.True(t, c.CompareAndSet("key", entry.Version(), []byte("pinned"), pubmodel.WithPin()))
+	require.True(t, entry.IsPinned())
+	require.Equal(t, entry.Weight(), c.PinnedMem())
+
+	c.Del("key")
+	require.Zero(t, c.PinnedMem())
+}
+
+// TestCache_Pin_ConcurrentWithinBudget never lets concurrent pins overshoot db.pinned_fraction together.
+func TestCache_Pin_ConcurrentWithinBudget(t *testing.T) {
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes:      10 * 1024 * 1024,
+			PinnedFraction: 0.1, // 1MiB
+		},
+	}
+	cfg.AdjustConfig()
+	c := New(context.Background(), cfg, slog.Default())
+
+	value := make([]byte, 100*1024)
+	for i := 0; i < 64; i++ {
+		require.True(t, c.Set("key-"+strconv.Itoa(i), value))
+	}
+
+	var (
+		wg     sync.WaitGroup
+		pinned atomic.Int64
+	)
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.Pin("key-" + strconv.Itoa(i)) {
+				pinned.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Positive(t, pinned.Load())
+	require.LessOrEqual(t, c.PinnedMem(), cfg.DB.PinnedLimitBytes)
+	entry, _ := c.peek(model.NewKey("key-0"))
+	require.Equal(t, pinned.Load()*entry.Weight(), c.PinnedMem())
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"github.com/Borislavv/go-ash-cache/internal/cache/db"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// Hill climbing of the W-TinyLFU window share (eviction.adaptive_window), as in Caffeine. The hit rate is sampled
+// per period of requests; the window moves by a step in the direction which improved the hit rate of the last
+// period and turns back once it got worse. The step decays each period, so the split settles down, and restarts
+// at full size once the hit rate jumps by climbRestartThreshold or more (the workload has shifted).
+const (
+	climbSampleRatio      = 10     // period length in requests by the number of entries
+	climbMinSample        = 1024   // period lower bound in requests
+	climbInitialStep      = 0.0625 // share of entries the window moves by after a (re)start
+	climbStepDecay        = 0.98   // step multiplier per period
+	climbRestartThreshold = 0.05   // hit rate change which restarts the climb
+	maxWindowFraction     = 0.8    // the main space keeps at least 20% of entries
+)
+
+type climber struct {
+	db *db.Map
+
+	requests atomic.Int64  // requests of the current period
+	hits     atomic.Int64  // hits of the current period
+	sample   atomic.Int64  // length of the current period in requests
+	hitRate  atomic.Uint64 // hit rate of the last period (float64 bits)
+
+	mu          sync.Mutex // guards climbing (prevHitRate, sampled and step)
+	prevHitRate float64
+	sampled     bool    // prevHitRate holds the hit rate of a period
+	step        float64 // signed: the direction and the size of the next move
+
+	grown    atomic.Int64 // periods which made the window bigger
+	shrunk   atomic.Int64 // periods which made the window smaller
+	restarts atomic.Int64 // steps reset to climbInitialStep on a workload shift
+}
+
+func newClimber(m *db.Map) *climber {
+	cl := &climber{db: m, step: climbInitialStep}
+	cl.sample.Store(climbMinSample)
+	return cl
+}
+
+// record counts a request of the current period and climbs once the period is over.
+func (cl *climber) record(hit bool) {
+	if hit {
+		cl.hits.Add(1)
+	}
+	if cl.requests.Add(1) < cl.sample.Load() || !cl.mu.TryLock() {
+		return
+	}
+	defer cl.mu.Unlock()
+
+	requests := cl.requests.Load()
+	if requests < cl.sample.Load() {
+		return // climbed already
+	}
+	hits := cl.hits.Swap(0)
+	cl.requests.Add(-requests)
+
+	cl.climb(float64(hits) / float64(requests))
+	cl.sample.Store(max(climbMinSample, climbSampleRatio*cl.db.Len()))
+}
+
+// climb moves the window by the hit rate of the last period. The first period only seeds the hit rate
+// to compare the next one with. Must be called under mu.
+func (cl *climber) climb(hitRate float64) {
+	cl.hitRate.Store(math.Float64bits(hitRate))
+	if !cl.sampled {
+		cl.prevHitRate, cl.sampled = hitRate, true
+		return
+	}
+
+	change := hitRate - cl.prevHitRate
+	amount := cl.step
+	if change < 0 {
+		amount = -amount // got worse: turn back
+	}
+	if math.Abs(change) >= climbRestartThreshold {
+		cl.step = math.Copysign(climbInitialStep, amount)
+		cl.restarts.Add(1)
+	} else {
+		cl.step = amount * climbStepDecay
+	}
+	cl.prevHitRate = hitRate
+
+	cur := cl.db.WindowFraction()
+	next := min(max(cur+amount, 0), maxWindowFraction)
+	cl.db.SetWindowFraction(next)
+	if next > cur {
+		cl.grown.Add(1)
+	} else if next < cur {
+		cl.shrunk.Add(1)
+	}
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"github.com/Borislavv/go-ash-cache/config"
+	"github.com/Borislavv/go-ash-cache/internal/cache/db"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newTestClimber() *climber {
+	cfg := &config.Cache{
+		DB:       config.DBCfg{SizeBytes: 1 << 20},
+		Eviction: &config.EvictionCfg{LRUMode: config.LRUModeWindowed, SoftLimitCoefficient: 1, AdaptiveWindow: true},
+	}
+	cfg.AdjustConfig()
+	return newClimber(db.NewMap(context.Background(), cfg))
+}
+
+// TestClimber_Climb_FollowsHitRate checks that the window keeps its direction while the hit rate improves,
+// turns back once it gets worse, decays the step and restarts it on a workload shift.
+func TestClimber_Climb_FollowsHitRate(t *testing.T) {
+	cl := newTestClimber()
+	require.Equal(t, config.DefaultWindowFraction, cl.db.WindowFraction())
+
+	cl.climb(0.5) // the first period: only the baseline
+	require.Equal(t, config.DefaultWindowFraction, cl.db.WindowFraction())
+	require.Zero(t, cl.restarts.Load())
+
+	cl.climb(0.52) // better: grow
+	require.InDelta(t, 0.01+climbInitialStep, cl.db.WindowFraction(), 1e-9)
+
+	cl.climb(0.51) // worse: turn back by the decayed step
+	require.InDelta(t, 0.01+climbInitialStep-climbInitialStep*climbStepDecay, cl.db.WindowFraction(), 1e-9)
+	require.InDelta(t, -climbInitialStep*climbStepDecay*climbStepDecay, cl.step, 1e-9)
+
+	cl.climb(0.3) // much worse: a shift, turn back with the initial step
+	require.InDelta(t, climbInitialStep, cl.step, 1e-9)
+
+	grown, shrunk, restarts := cl.grown.Load(), cl.shrunk.Load(), cl.restarts.Load()
+	require.EqualValues(t, 2, grown)
+	require.EqualValues(t, 1, shrunk)
+	require.EqualValues(t, 1, restarts)
+}
+
+// TestClimber_Climb_Bounded checks the window stays within [0, maxWindowFraction].
+func TestClimber_Climb_Bounded(t *testing.T) {
+	cl := newTestClimber()
+	for i := 0; i < 100; i++ {
+		cl.climb(float64(i) / 100) // always better: keep growing
+	}
+	require.Equal(t, maxWindowFraction, cl.db.WindowFraction())
+
+	cl = newTestClimber()
+	cl.step = -climbInitialStep
+	for i := 0; i < 100; i++ {
+		cl.climb(float64(i) / 100)
+	}
+	require.Zero(t, cl.db.WindowFraction())
+}
+
+// TestClimber_Record_ClimbsPerPeriod checks the climber samples the hit rate per period of requests.
+func TestClimber_Record_ClimbsPerPeriod(t *testing.T) {
+	cl := newTestClimber()
+	for i := 0; i < climbMinSample-1; i++ {
+		cl.record(i%4 == 0)
+	}
+	require.False(t, cl.sampled, "the period is not over yet")
+
+	cl.record(false)
+	require.True(t, cl.sampled)
+	require.InDelta(t, 0.25, cl.prevHitRate, 1e-9)
+	require.Equal(t, config.DefaultWindowFraction, cl.db.WindowFraction(), "the first period is the baseline")
+	require.Zero(t, cl.requests.Load())
+	require.Zero(t, cl.hits.Load())
+
+	for i := 0; i < climbMinSample; i++ {
+		cl.record(i%2 == 0)
+	}
+	require.NotEqual(t, config.DefaultWindowFraction, cl.db.WindowFraction())
+	require.InDelta(t, 0.5, cl.prevHitRate, 1e-9)
+}
+
+// TestClimber_Record_StableWorkload checks a stable hit rate never restarts the climb.
+func TestClimber_Record_StableWorkload(t *testing.T) {
+	cl := newTestClimber()
+	for i := 0; i < 10*climbMinSample; i++ {
+		cl.record(i%4 == 0)
+	}
+	require.EqualValues(t, 10, cl.grown.Load()+cl.shrunk.Load()+1, "each period but the first moves the window")
+	require.Zero(t, cl.restarts.Load())
+}
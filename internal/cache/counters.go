@@ -7,6 +7,16 @@ type counters struct {
 	admissionNotAllowed   atomic.Int64
 	evictedHardLimitItems atomic.Int64
 	evictedHardLimitBytes atomic.Int64
+	coalesced             atomic.Int64 // loader calls deduplicated by the in-flight group
+	negativeHits          atomic.Int64 // loader errors replayed from tombstones
+	negativeStored        atomic.Int64 // tombstones stored
+	staleHits             atomic.Int64 // stale payloads served within the stale window
+	revalidated           atomic.Int64 // successful background revalidations
+	revalidationsDropped  atomic.Int64 // revalidations not scheduled since the queue was full
+	refreshFailures       atomic.Int64 // failed refreshes backed off by stale-if-error
+	graceExpired          atomic.Int64 // entries removed after failing refreshes beyond the grace period
+	lazyExpired           atomic.Int64 // expired entries removed by reads ahead of the lifetimer
+	maxStaleEvicted       atomic.Int64 // entries evicted by the lifetimer beyond lifetime.max_stale
 }
 
 func newCounters() *counters {
@@ -15,6 +25,16 @@ func newCounters() *counters {
 		admissionNotAllowed:   atomic.Int64{},
 		evictedHardLimitItems: atomic.Int64{},
 		evictedHardLimitBytes: atomic.Int64{},
+		coalesced:             atomic.Int64{},
+		negativeHits:          atomic.Int64{},
+		negativeStored:        atomic.Int64{},
+		staleHits:             atomic.Int64{},
+		revalidated:           atomic.Int64{},
+		revalidationsDropped:  atomic.Int64{},
+		refreshFailures:       atomic.Int64{},
+		graceExpired:          atomic.Int64{},
+		lazyExpired:           atomic.Int64{},
+		maxStaleEvicted:       atomic.Int64{},
 	}
 }
 
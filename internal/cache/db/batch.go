@@ -0,0 +1,120 @@
+package db
+
+import (
+	"cmp"
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
+	"slices"
+	"sync/atomic"
+)
+
+// GetMany reads values of a batch of full keys: out[i] is the value of keys[i] or nil.
+// Each involved shard is read-locked once per batch.
+func (m *Map) GetMany(keys []*pubmodel.Key, out []*model.Entry) {
+	m.walkGrouped(values(keys), func(sh *Shard, positions []int) {
+		sh.RLock()
+		for _, i := range positions {
+			out[i], _ = sh.findUnlocked(keys[i].Value(), keys[i])
+		}
+		sh.RUnlock()
+	})
+}
+
+// SetMany inserts/updates a batch of values and adjusts global counters.
+// Each involved shard is locked once per batch.
+func (m *Map) SetMany(values []*model.Entry) {
+	keys := make([]uint64, len(values))
+	for i, v := range values {
+		keys[i] = v.Key().Value()
+	}
+	m.walkGrouped(keys, func(sh *Shard, positions []int) {
+		var bytesDelta, lenDelta int64
+		sh.Lock()
+		for _, i := range positions {
+			b, l := sh.SetUnlocked(keys[i], values[i])
+			bytesDelta += b
+			lenDelta += l
+		}
+		sh.Unlock()
+		if bytesDelta != 0 {
+			atomic.AddInt64(&m.mem, bytesDelta)
+		}
+		if lenDelta != 0 {
+			atomic.AddInt64(&m.len, lenDelta)
+		}
+	})
+}
+
+// RemoveMany deletes a batch of full keys and adjusts global counters.
+// Each involved shard is locked once per batch.
+func (m *Map) RemoveMany(keys []*pubmodel.Key) (freedBytes, removed int64) {
+	m.walkGrouped(values(keys), func(sh *Shard, positions []int) {
+		var shardFreed, shardRemoved int64
+		sh.Lock()
+		for _, i := range positions {
+			entry, found := sh.findUnlocked(keys[i].Value(), keys[i])
+			if !found {
+				continue
+			}
+			if freed, hit := sh.removeEntryUnlocked(keys[i].Value(), entry); hit {
+				shardFreed += freed
+				shardRemoved++
+			}
+		}
+		sh.Unlock()
+		if shardRemoved != 0 {
+			atomic.AddInt64(&m.len, -shardRemoved)
+			atomic.AddInt64(&m.mem, -shardFreed)
+		}
+		freedBytes += shardFreed
+		removed += shardRemoved
+	})
+	return
+}
+
+// TouchMany moves a batch of keys to front in LRU lists (listing and windowed modes only).
+// Like touchLRU it is best-effort: a busy shard is skipped.
+func (m *Map) TouchMany(keys []uint64) {
+	if m.mode == Sampling {
+		return
+	}
+	m.walkGrouped(keys, func(sh *Shard, positions []int) {
+		if !sh.isOrdered() || !sh.TryLock() {
+			return
+		}
+		for _, i := range positions {
+			sh.lruOnAccessUnlocked(keys[i])
+		}
+		sh.Unlock()
+	})
+}
+
+func values(keys []*pubmodel.Key) []uint64 {
+	out := make([]uint64, len(keys))
+	for i, k := range keys {
+		out[i] = k.Value()
+	}
+	return out
+}
+
+// walkGrouped calls fn once per shard owning at least one of the keys,
+// with positions of the keys which belong to that shard.
+func (m *Map) walkGrouped(keys []uint64, fn func(sh *Shard, positions []int)) {
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Compare(keys[a]&shardMask, keys[b]&shardMask)
+	})
+
+	for from := 0; from < len(order); {
+		id := keys[order[from]] & shardMask
+		to := from + 1
+		for to < len(order) && keys[order[to]]&shardMask == id {
+			to++
+		}
+		fn(m.shards[id], order[from:to])
+		from = to
+	}
+}
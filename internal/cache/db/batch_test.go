@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"github.com/Borislavv/go-ash-cache/config"
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestMap_Batch_SetGetRemoveMany keeps results in input order and counters in sync across shards.
+func TestMap_Batch_SetGetRemoveMany(t *testing.T) {
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024,
+		},
+		Eviction: &config.EvictionCfg{
+			LRUMode: config.LRUModeListing,
+		},
+	}
+	cfg.AdjustConfig()
+
+	m := NewMap(context.Background(), cfg)
+
+	// keys of the same shard interleaved with keys of other shards
+	keys := []uint64{5, 5 + NumOfShards, 7, 5 + 2*NumOfShards, 1}
+	values := make([]*model.Entry, len(keys))
+	for i := range keys {
+		values[i] = model.NewEntry(pubmodel.NewKey(keys[i], 0, 0), 0, false)
+		values[i].SetPayload(make([]byte, 16))
+	}
+
+	m.SetMany(values)
+	require.Equal(t, int64(len(keys)), m.Len())
+
+	var weight int64
+	for _, v := range values {
+		weight += v.Weight()
+	}
+	require.Equal(t, weight, m.Mem())
+
+	full := make([]*pubmodel.Key, 0, len(keys)+1)
+	for _, v := range values {
+		full = append(full, v.Key())
+	}
+	out := make([]*model.Entry, len(keys)+1)
+	m.GetMany(append(full, pubmodel.NewKey(42, 0, 0)), out)
+	for i := range keys {
+		require.Same(t, values[i], out[i])
+	}
+	require.Nil(t, out[len(keys)])
+
+	m.TouchMany(keys)
+
+	freed, removed := m.RemoveMany([]*pubmodel.Key{full[1], full[4], pubmodel.NewKey(42, 0, 0)})
+	require.Equal(t, int64(2), removed)
+	require.Equal(t, values[1].Weight()+values[4].Weight(), freed)
+	require.Equal(t, int64(len(keys)-2), m.Len())
+	require.Equal(t, weight-freed, m.Mem())
+}
+
+// TestMap_ComputeMany_LocksShardOnce runs fn for all keys of a shard under a single lock of it.
+func TestMap_ComputeMany_LocksShardOnce(t *testing.T) {
+	cfg := &config.Cache{DB: config.DBCfg{SizeBytes: 10 * 1024 * 1024}}
+	cfg.AdjustConfig()
+	m := NewMap(context.Background(), cfg)
+
+	// keys of the same shard interleaved with keys of other shards; the first two are stored already
+	hashes := []uint64{5, 7, 5 + NumOfShards, 1, 5 + 2*NumOfShards, 7 + NumOfShards}
+	keys := make([]*pubmodel.Key, len(hashes))
+	values := make([]*model.Entry, len(hashes))
+	for i, h := range hashes {
+		keys[i] = pubmodel.NewKey(h, 0, 0)
+		values[i] = model.NewEntry(keys[i], 0, false)
+		values[i].SetPayload(make([]byte, 16))
+	}
+	m.SetMany(values[:2])
+
+	var (
+		runs    []uint64 // shards in the order of fn calls, one run per shard
+		visited = make([]bool, len(keys))
+	)
+	m.ComputeMany(keys, func(i int, cur *model.Entry) *model.Entry {
+		sh := m.Shard(hashes[i])
+		require.False(t, sh.TryLock(), "fn runs under the shard lock")
+		if len(runs) == 0 || runs[len(runs)-1] != sh.ID() {
+			require.NotContains(t, runs, sh.ID(), "a shard is locked once per batch")
+			runs = append(runs, sh.ID())
+		}
+		visited[i] = true
+		if i < 2 {
+			require.Same(t, values[i], cur)
+			return cur
+		}
+		require.Nil(t, cur)
+		return values[i]
+	})
+	require.Equal(t, []uint64{1, 5, 7}, runs)
+	require.Equal(t, []bool{true, true, true, true, true, true}, visited)
+	require.Equal(t, int64(len(keys)), m.Len())
+}
@@ -55,8 +55,9 @@ func (a *ShardedAdmitter) Record(h uint64) {
 }
 
 // Allow returns true if the candidate should replace a victim according to TinyLFU.
-// If the candidate is unseen by the doorkeeper we conservatively reject (unless caller
-// uses a small “window” segment to bypass admission).
+// If the candidate is unseen by the doorkeeper we conservatively reject. On insert this turns
+// away every new key, so the "wtinylfu" eviction mode asks it only on promotion from its
+// admission window, where new keys have already gathered some frequency.
 func (a *ShardedAdmitter) Allow(candidate, victim uint64) bool {
 	if candidate == victim {
 		// Same entry: no replacement needed, but "allow" is safe.
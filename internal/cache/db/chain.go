@@ -0,0 +1,140 @@
+package db
+
+import (
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
+	"sync/atomic"
+)
+
+// Entries whose keys share the same 64-bit hash are chained in one map slot (see model.Entry.Next)
+// and told apart by the full 128-bit key. A slot is the unit of LRU and refresh queues:
+// touching any entry of a chain moves the slot, popping the LRU tail evicts the whole chain.
+
+// findUnlocked returns the entry of the slot with the same full key. Requires at least the shared lock.
+func (sh *Shard) findUnlocked(key uint64, k *pubmodel.Key) (*model.Entry, bool) {
+	for e := sh.items[key]; e != nil; e = e.Next() {
+		if e.Key().IsTheSame(k) {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// linkUnlocked puts a new entry to the head of the chain of the slot and reports whether it collided with others.
+func (sh *Shard) linkUnlocked(key uint64, new *model.Entry) (collided bool) {
+	head, collided := sh.items[key]
+	new.Link(head)
+	sh.items[key] = new
+	sh.indexUnlocked(new)
+	if collided {
+		atomic.AddInt64(&sh.collisions, 1)
+	}
+	sh.lruOnInsertUnlocked(key)
+	return collided
+}
+
+// replaceUnlocked puts new in place of old within the chain of the slot.
+func (sh *Shard) replaceUnlocked(key uint64, old, new *model.Entry) {
+	if old == new {
+		return
+	}
+	if old.IsPinned() {
+		new.SetPinned(true) // pinning belongs to the key
+	}
+	sh.unindexUnlocked(old)
+	sh.indexUnlocked(new)
+	new.Link(old.Next())
+	old.Link(nil)
+	if sh.items[key] == old {
+		sh.items[key] = new
+		return
+	}
+	for e := sh.items[key]; e != nil; e = e.Next() {
+		if e.Next() == old {
+			e.Link(new)
+			return
+		}
+	}
+}
+
+// unlinkUnlocked removes the entry from the chain of the slot; the slot is deleted with its last entry.
+func (sh *Shard) unlinkUnlocked(key uint64, entry *model.Entry) (hit bool) {
+	head := sh.items[key]
+	if head == nil {
+		return false
+	}
+	if head == entry {
+		if next := entry.Next(); next != nil {
+			sh.items[key] = next
+		} else {
+			delete(sh.items, key)
+			sh.lruOnDeleteUnlocked(key)
+		}
+		entry.Link(nil)
+		sh.unindexUnlocked(entry)
+		return true
+	}
+	for e := head; e.Next() != nil; e = e.Next() {
+		if e.Next() == entry {
+			e.Link(entry.Next())
+			entry.Link(nil)
+			sh.unindexUnlocked(entry)
+			return true
+		}
+	}
+	return false
+}
+
+// removeEntryUnlocked deletes the given entry of the slot.
+func (sh *Shard) removeEntryUnlocked(key uint64, entry *model.Entry) (freedBytes int64, hit bool) {
+	if hit = sh.unlinkUnlocked(key, entry); hit {
+		freedBytes = entry.Weight()
+		atomic.AddInt64(&sh.mem, -freedBytes)
+		atomic.AddInt64(&sh.len, -1)
+	}
+	return
+}
+
+// removeSlotUnlocked deletes the slot with all chained entries.
+func (sh *Shard) removeSlotUnlocked(key uint64) (freedBytes, removed int64) {
+	head, hit := sh.items[key]
+	if !hit {
+		return 0, 0
+	}
+	delete(sh.items, key)
+	sh.lruOnDeleteUnlocked(key)
+	sh.unindexChainUnlocked(head)
+
+	freedBytes, removed = chainWeight(head)
+	atomic.AddInt64(&sh.mem, -freedBytes)
+	atomic.AddInt64(&sh.len, -removed)
+	return
+}
+
+// indexUnlocked adds a linked entry to the indexes of the shard (tags, pinned entries).
+func (sh *Shard) indexUnlocked(entry *model.Entry) {
+	sh.tagUnlocked(entry)
+	sh.pinUnlocked(entry)
+}
+
+// unindexUnlocked drops an unlinked entry from the indexes of the shard.
+func (sh *Shard) unindexUnlocked(entry *model.Entry) {
+	sh.untagUnlocked(entry)
+	sh.unpinUnlocked(entry)
+}
+
+// unindexChainUnlocked drops all entries of a removed chain from the indexes of the shard.
+func (sh *Shard) unindexChainUnlocked(head *model.Entry) {
+	for e := head; e != nil; e = e.Next() {
+		sh.unindexUnlocked(e)
+	}
+}
+
+// chainWeight sums weights of the chain starting at head.
+func chainWeight(head *model.Entry) (bytes, entries int64) {
+	for e := head; e != nil; e = e.Next() {
+		bytes += e.Weight()
+		entries++
+	}
+	return
+}
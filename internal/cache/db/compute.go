@@ -0,0 +1,69 @@
+package db
+
+import (
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
+	"sync/atomic"
+)
+
+// Compute runs fn under the write lock of the shard owning k and adjusts global counters.
+// fn receives the entry of the key (nil if absent) and returns the entry to hold: the same entry keeps it
+// (fn may change it in place, account the weight diff by AddMem and pin it by SetPinned; the pinned budget
+// is synced), another one replaces or inserts it, nil removes it. fn must not call the map.
+func (m *Map) Compute(k *pubmodel.Key, fn func(cur *model.Entry) (next *model.Entry)) {
+	bytesDelta, lenDelta := m.Shard(k.Value()).Compute(k, fn)
+	if bytesDelta != 0 {
+		atomic.AddInt64(&m.mem, bytesDelta)
+	}
+	if lenDelta != 0 {
+		atomic.AddInt64(&m.len, lenDelta)
+	}
+}
+
+// ComputeMany is the batch counterpart of Compute: fn receives the position of the key in keys along with its entry.
+// Each involved shard is locked once per batch.
+func (m *Map) ComputeMany(keys []*pubmodel.Key, fn func(i int, cur *model.Entry) (next *model.Entry)) {
+	m.walkGrouped(values(keys), func(sh *Shard, positions []int) {
+		var bytesDelta, lenDelta int64
+		sh.Lock()
+		for _, i := range positions {
+			b, l := sh.computeUnlocked(keys[i], func(cur *model.Entry) *model.Entry { return fn(i, cur) })
+			bytesDelta += b
+			lenDelta += l
+		}
+		sh.Unlock()
+		if bytesDelta != 0 {
+			atomic.AddInt64(&m.mem, bytesDelta)
+		}
+		if lenDelta != 0 {
+			atomic.AddInt64(&m.len, lenDelta)
+		}
+	})
+}
+
+// Compute is the per-shard counterpart of Map.Compute. Returns deltas for global aggregations.
+func (sh *Shard) Compute(k *pubmodel.Key, fn func(cur *model.Entry) (next *model.Entry)) (bytesDelta, lenDelta int64) {
+	sh.Lock()
+	defer sh.Unlock()
+	return sh.computeUnlocked(k, fn)
+}
+
+func (sh *Shard) computeUnlocked(k *pubmodel.Key, fn func(cur *model.Entry) (next *model.Entry)) (bytesDelta, lenDelta int64) {
+	key := k.Value()
+	cur, _ := sh.findUnlocked(key, k)
+	next := fn(cur)
+	switch {
+	case next == cur:
+		if cur != nil {
+			sh.lruOnAccessUnlocked(key)
+			sh.syncPinnedUnlocked(cur)
+		}
+	case next == nil:
+		if freedBytes, hit := sh.removeEntryUnlocked(key, cur); hit {
+			bytesDelta, lenDelta = -freedBytes, -1
+		}
+	default:
+		bytesDelta, lenDelta = sh.SetUnlocked(key, next)
+	}
+	return
+}
@@ -2,15 +2,25 @@ package db
 
 import (
 	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	"github.com/Borislavv/go-ash-cache/internal/shared/cachedtime"
+	"math"
 	"runtime"
 	"sync/atomic"
 )
 
 const shardsSample, keysSample = 4, 8
 
+// pinnedSkips bounds the number of LRU tail slots looked through by one victim probe (see pickVictimByList).
+const pinnedSkips = 8
+
+// tailCandidates is the number of unpinned LRU tail slots an eviction in Listing mode picks its victim from.
+const tailCandidates = 4
+
 func (m *Map) EvictUntilWithinLimit(limit, backoff int64) (freed, evicted int64) {
 	if m.mode == Listing {
 		return m.evictUntilWithinLimitByList(limit, backoff)
+	} else if m.mode == Windowed {
+		return m.evictUntilWithinLimitByWindow(limit, backoff)
 	} else {
 		return m.evictUntilWithinLimitBySample(limit, backoff)
 	}
@@ -37,11 +47,43 @@ func (m *Map) evictUntilWithinLimitByList(limit, backoff int64) (freed, evicted
 			continue
 		}
 		if _, v, ok := sh.lruPopTail(); ok {
-			w := v.Weight()
+			w, n := chainWeight(v)
 			atomic.AddInt64(&m.mem, -w)
-			atomic.AddInt64(&m.len, -1)
+			atomic.AddInt64(&m.len, -n)
 			freed += w
-			evicted++
+			evicted += n
+		}
+		backoff--
+	}
+	return
+}
+
+func (m *Map) evictUntilWithinLimitByWindow(limit, backoff int64) (freed, evicted int64) {
+	if m.mode != Windowed {
+		return 0, 0
+	}
+
+	// min over eviction (8MiB)
+	var minLimit int64 = 8 << 20
+
+	// eviction loop
+	for backoff > 0 {
+		curUsage := atomic.LoadInt64(&m.mem)
+		if (curUsage <= limit && freed <= minLimit) || m.Len() == 0 {
+			return freed, evicted
+		}
+		sh := m.NextShard()
+		if sh.Len() == 0 {
+			backoff--
+			runtime.Gosched()
+			continue
+		}
+		if _, v, ok := sh.windowPop(m.admitter); ok {
+			w, n := chainWeight(v)
+			atomic.AddInt64(&m.mem, -w)
+			atomic.AddInt64(&m.len, -n)
+			freed += w
+			evicted += n
 		}
 		backoff--
 	}
@@ -59,7 +101,12 @@ func (m *Map) evictUntilWithinLimitBySample(limit, backoff int64) (freed, evicte
 			backoff--
 			continue
 		}
-		bytesFreed, hit := sh.RemoveUnlocked(victim.Key().Value())
+		if victim.IsPinned() { // pinned since it was picked
+			sh.Unlock()
+			backoff--
+			continue
+		}
+		bytesFreed, hit := sh.removeEntryUnlocked(victim.Key().Value(), victim)
 		sh.Unlock()
 		if bytesFreed > 0 || hit {
 			atomic.AddInt64(&m.mem, -bytesFreed)
@@ -72,16 +119,20 @@ func (m *Map) evictUntilWithinLimitBySample(limit, backoff int64) (freed, evicte
 	return freed, evicted
 }
 
+// PickVictim samples a few entries and returns the best one to evict: an expired entry goes first
+// (it would be removed or served stale anyway), then the one idle for the longest time weighted
+// by its priority. A pinned entry is never offered.
 func (m *Map) PickVictim(shardsSample, keysSample int64) (bestShard *Shard, victim *model.Entry, ok bool) {
-	if m.mode == Listing {
+	if m.mode != Sampling {
 		return m.pickVictimByList()
 	} else {
 		return m.pickVictimBySample(shardsSample, keysSample)
 	}
 }
 
+// pickVictimByList probes list tails (in Windowed mode tails of the main space go first).
 func (m *Map) pickVictimByList() (bestShard *Shard, victim *model.Entry, ok bool) {
-	if m.mode != Listing {
+	if m.mode == Sampling {
 		return nil, victim, false
 	}
 
@@ -89,10 +140,12 @@ func (m *Map) pickVictimByList() (bestShard *Shard, victim *model.Entry, ok bool
 	start := int((atomic.AddUint64(&m.iter, 1) - 1) & shardMask)
 
 	var (
-		haveBest bool
-		bestAt   int64
-		bestV    *model.Entry
-		bestSh   *Shard
+		haveBest    bool
+		bestExpired bool
+		bestScore   float64
+		bestV       *model.Entry
+		bestSh      *Shard
+		now         = cachedtime.UnixNano()
 	)
 
 	for i := 0; i < probes; i++ {
@@ -100,10 +153,10 @@ func (m *Map) pickVictimByList() (bestShard *Shard, victim *model.Entry, ok bool
 		if sh.Len() == 0 {
 			continue
 		}
-		if _, v, ok2 := sh.lruPeekTail(); ok2 {
-			at := v.TouchedAt()
-			if !haveBest || at < bestAt {
-				haveBest, bestAt, bestV, bestSh = true, at, v, sh
+		if v, ok2 := sh.lruPeekTailK(pinnedSkips, isEvictable); ok2 {
+			score, expired := victimScore(v, now), v.Staleness() > 0
+			if !haveBest || isBetterVictim(expired, score, bestExpired, bestScore) {
+				haveBest, bestExpired, bestScore, bestV, bestSh = true, expired, score, v, sh
 			}
 		}
 	}
@@ -120,10 +173,12 @@ func (m *Map) pickVictimBySample(shardsSample, keysSample int64) (bestShard *Sha
 	}
 
 	var (
-		bestV    *model.Entry
-		bestAt   int64
-		bestSh   *Shard
-		haveBest bool
+		bestV       *model.Entry
+		bestScore   float64
+		bestExpired bool
+		bestSh      *Shard
+		haveBest    bool
+		now         = cachedtime.UnixNano()
 	)
 
 	for i := int64(0); i < shardsSample; i++ {
@@ -146,14 +201,19 @@ func (m *Map) pickVictimBySample(shardsSample, keysSample int64) (bestShard *Sha
 			toScanPerShard = shardLen
 		}
 
-		for _, reviewEntry := range sh.items {
-			at := reviewEntry.TouchedAt()
-			if !haveBest || at < bestAt {
-				bestV, bestAt, bestSh, haveBest = reviewEntry, at, sh, true
-			}
-
-			if toScanPerShard--; toScanPerShard <= 0 {
-				break
+	scan:
+		for _, head := range sh.items {
+			for reviewEntry := head; reviewEntry != nil; reviewEntry = reviewEntry.Next() {
+				if !reviewEntry.IsPinned() {
+					score, expired := victimScore(reviewEntry, now), reviewEntry.Staleness() > 0
+					if !haveBest || isBetterVictim(expired, score, bestExpired, bestScore) {
+						bestV, bestScore, bestExpired, bestSh, haveBest = reviewEntry, score, expired, sh, true
+					}
+				}
+
+				if toScanPerShard--; toScanPerShard <= 0 {
+					break scan
+				}
 			}
 		}
 		sh.RUnlock()
@@ -164,3 +224,20 @@ func (m *Map) pickVictimBySample(shardsSample, keysSample int64) (bestShard *Sha
 	}
 	return bestSh, bestV, true
 }
+
+// isBetterVictim compares a candidate with the best victim found so far: expired before fresh,
+// then the higher score (see victimScore).
+func isBetterVictim(expired bool, score float64, bestExpired bool, bestScore float64) bool {
+	if expired != bestExpired {
+		return expired
+	}
+	return score > bestScore
+}
+
+// victimScore is the time the entry has been idle weighted by its priority:
+// each level above normal halves it, each level below doubles it.
+func victimScore(v *model.Entry, now int64) float64 {
+	return math.Ldexp(float64(max(now-v.TouchedAt(), 0)), -int(v.Priority()))
+}
+
+func isEvictable(v *model.Entry) bool { return !v.IsPinned() }
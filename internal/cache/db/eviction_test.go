@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/Borislavv/go-ash-cache/config"
 	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
@@ -42,6 +43,37 @@ func TestMap_EvictUntilWithinLimit_ListingMode(t *testing.T) {
 	require.LessOrEqual(t, m.Mem(), cfg.Eviction.SoftMemoryLimitBytes, "should be within limit")
 }
 
+// TestMap_EvictUntilWithinLimit_WindowedMode evicts entries in windowed (W-TinyLFU) mode.
+func TestMap_EvictUntilWithinLimit_WindowedMode(t *testing.T) {
+	cfg := &config.Cache{
+		DB: config.DBCfg{
+			SizeBytes: 10 * 1024 * 1024, // 10MB
+		},
+		Eviction: &config.EvictionCfg{
+			LRUMode:              config.LRUModeWindowed,
+			SoftLimitCoefficient: 0.8,
+		},
+	}
+	cfg.AdjustConfig()
+
+	ctx := context.Background()
+	m := NewMap(ctx, cfg)
+	require.Equal(t, config.DefaultWindowFraction, m.WindowFraction())
+
+	// Fill cache with entries
+	for i := 0; i < 100; i++ {
+		entry := model.NewEntry(model.NewKey("test"), 0, false)
+		entry.SetPayload(make([]byte, 100*1024)) // 100KB each
+		m.Set(uint64(i), entry)
+	}
+
+	freed, evicted := m.EvictUntilWithinLimit(cfg.Eviction.SoftMemoryLimitBytes, 10000)
+
+	require.Greater(t, evicted, int64(0), "should evict some entries")
+	require.Greater(t, freed, int64(0), "should free memory")
+	require.LessOrEqual(t, m.Mem(), cfg.Eviction.SoftMemoryLimitBytes, "should be within limit")
+}
+
 // TestMap_EvictUntilWithinLimit_SamplingMode evicts entries in sampling mode.
 func TestMap_EvictUntilWithinLimit_SamplingMode(t *testing.T) {
 	cfg := &config.Cache{
@@ -218,3 +250,58 @@ func TestMap_EvictUntilWithinLimit_StopsWhenEmpty(t *testing.T) {
 		require.Greater(t, freed, int64(0))
 	}
 }
+
+// TestIsBetterVictim prefers an expired entry over a fresh one, then the higher score.
+func TestIsBetterVictim(t *testing.T) {
+	require.True(t, isBetterVictim(true, 100, false, 200), "expired goes before fresh however high its score")
+	require.False(t, isBetterVictim(false, 200, true, 100), "fresh never replaces an expired victim")
+	require.True(t, isBetterVictim(false, 200, false, 100), "the higher score among fresh")
+	require.True(t, isBetterVictim(true, 200, true, 100), "the higher score among expired")
+	require.False(t, isBetterVictim(true, 200, true, 200))
+}
+
+// TestVictimScore weights idle time by priority.
+func TestVictimScore(t *testing.T) {
+	entry := model.NewEntry(model.NewKey("test"), 0, false)
+	entry.SetPayload([]byte("data"))
+	now := entry.TouchedAt() + 1000
+
+	require.Equal(t, float64(1000), victimScore(entry, now))
+	entry.SetPriority(pubmodel.PriorityHigh)
+	require.Equal(t, float64(500), victimScore(entry, now))
+	entry.SetPriority(pubmodel.PriorityLow)
+	require.Equal(t, float64(2000), victimScore(entry, now))
+}
+
+// TestMap_Pinned_NeverEvicted skips pinned entries in both LRU modes and accounts their weight.
+func TestMap_Pinned_NeverEvicted(t *testing.T) {
+	for _, mode := range []config.LRUMode{config.LRUModeListing, config.LRUModeSampling} {
+		cfg := &config.Cache{DB: config.DBCfg{SizeBytes: 1024 * 1024}, Eviction: &config.EvictionCfg{LRUMode: mode}}
+		cfg.AdjustConfig()
+		m := NewMap(context.Background(), cfg)
+
+		pinned := model.NewEntry(model.NewKey("pinned"), 0, false)
+		pinned.Pin()
+		pinned.SetPayload([]byte("data"))
+		m.Set(pinned.Key().Value(), pinned)
+		require.Equal(t, pinned.Weight(), m.PinnedMem())
+
+		for i := 0; i < 10; i++ {
+			_, victim, ok := m.PickVictim(NumOfShards, 8)
+			require.False(t, ok && victim == pinned, "a pinned entry is never offered as a victim")
+		}
+		freed, evicted := m.EvictUntilWithinLimit(0, 4*NumOfShards)
+		require.Zero(t, freed)
+		require.Zero(t, evicted)
+		require.Equal(t, int64(1), m.Len())
+
+		// evictable once unpinned
+		require.True(t, m.Unpin(pinned.Key()))
+		require.Zero(t, m.PinnedMem())
+		var victim *model.Entry
+		for i := 0; i < NumOfShards && victim == nil; i++ { // listing mode probes a few shards per call
+			_, victim, _ = m.PickVictim(NumOfShards, 8)
+		}
+		require.Same(t, pinned, victim)
+	}
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"container/list"
 	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	"github.com/Borislavv/go-ash-cache/internal/shared/cachedtime"
 	"sync/atomic"
 )
 
@@ -11,6 +12,7 @@ type LRUMode int
 const (
 	Listing LRUMode = iota
 	Sampling
+	Windowed
 )
 
 func (sh *Shard) enableLRU() {
@@ -38,6 +40,10 @@ func (sh *Shard) disableLRU() {
 
 // lruOnInsertUnlocked - is unsafe without shard.Lock due to it mutates the list.
 func (sh *Shard) lruOnInsertUnlocked(key uint64) {
+	if sh.win != nil {
+		sh.win.insert(key)
+		return
+	}
 	if !sh.lruOn || sh.lru == nil {
 		return
 	}
@@ -50,6 +56,10 @@ func (sh *Shard) lruOnInsertUnlocked(key uint64) {
 
 // lruOnAccessUnlocked - is unsafe without shard.Lock due to it mutates the list otherwise use touchLRU.
 func (sh *Shard) lruOnAccessUnlocked(key uint64) {
+	if sh.win != nil {
+		sh.win.access(key)
+		return
+	}
 	if !sh.lruOn || sh.lru == nil {
 		return
 	}
@@ -60,6 +70,10 @@ func (sh *Shard) lruOnAccessUnlocked(key uint64) {
 
 // lruOnDeleteUnlocked - is unsafe without shard.Lock due to it mutates the list.
 func (sh *Shard) lruOnDeleteUnlocked(key uint64) {
+	if sh.win != nil {
+		sh.win.remove(key)
+		return
+	}
 	if !sh.lruOn || sh.lru == nil {
 		return
 	}
@@ -71,17 +85,20 @@ func (sh *Shard) lruOnDeleteUnlocked(key uint64) {
 
 // touchLRU - threadsafe.
 func (sh *Shard) touchLRU(key uint64) {
-	if !sh.lruOn || sh.lru == nil {
+	if !sh.isOrdered() {
 		return
 	}
 	if sh.TryLock() {
-		if el := sh.lidx[key]; el != nil {
-			sh.lru.MoveToFront(el)
-		}
+		sh.lruOnAccessUnlocked(key)
 		sh.Unlock()
 	}
 }
 
+// isOrdered reports whether the shard keeps its slots in recency lists (Listing and Windowed modes).
+func (sh *Shard) isOrdered() bool {
+	return sh.win != nil || (sh.lruOn && sh.lru != nil)
+}
+
 // tail ops for eviction/refresh
 func (sh *Shard) lruPeekTail() (key uint64, val *model.Entry, ok bool) {
 	if !sh.lruOn || sh.lru == nil {
@@ -101,31 +118,62 @@ func (sh *Shard) lruPeekTail() (key uint64, val *model.Entry, ok bool) {
 	return k, v, true
 }
 
+// lruPopTail evicts the slot chosen by lruVictimUnlocked.
 func (sh *Shard) lruPopTail() (key uint64, val *model.Entry, ok bool) {
 	if !sh.lruOn || sh.lru == nil {
 		return 0, nil, false
 	}
 	sh.Lock()
 	defer sh.Unlock()
-	el := sh.lru.Back()
+	el := sh.lruVictimUnlocked()
 	if el == nil {
 		return 0, nil, false
 	}
 	k := el.Value.(uint64)
-	v, ok := sh.items[k]
-	if !ok {
-		sh.lru.Remove(el)
-		delete(sh.lidx, k)
-		return 0, nil, false
-	}
+	v := sh.items[k]
+	// the whole collision chain goes with its slot
 	delete(sh.items, k)
-	atomic.AddInt64(&sh.len, -1)
-	atomic.AddInt64(&sh.mem, -v.Weight())
+	sh.unindexChainUnlocked(v)
+	freed, n := chainWeight(v)
+	atomic.AddInt64(&sh.len, -n)
+	atomic.AddInt64(&sh.mem, -freed)
 	sh.lru.Remove(el)
 	delete(sh.lidx, k)
 	return k, v, true
 }
 
+// lruVictimUnlocked returns the best victim (see isBetterVictim) among up to tailCandidates unpinned slots
+// of the LRU tail, so priorities count in Listing mode too. Pinned slots met on the way are moved to the front,
+// so they don't clog the tail; each slot is visited once at most, so only a shard of pinned slots has no victim.
+func (sh *Shard) lruVictimUnlocked() *list.Element {
+	var (
+		best        *list.Element
+		bestScore   float64
+		bestExpired bool
+		now         = cachedtime.UnixNano()
+	)
+	el := sh.lru.Back()
+	for visited, candidates := 0, 0; el != nil && visited < sh.lru.Len() && candidates < tailCandidates; visited++ {
+		prev := el.Prev()
+		k := el.Value.(uint64)
+		switch head, ok := sh.items[k]; {
+		case !ok:
+			sh.lru.Remove(el)
+			delete(sh.lidx, k)
+		case isChainPinned(head):
+			sh.lru.MoveToFront(el)
+		default:
+			candidates++
+			score, expired := victimScore(head, now), head.Staleness() > 0
+			if best == nil || isBetterVictim(expired, score, bestExpired, bestScore) {
+				best, bestScore, bestExpired = el, score, expired
+			}
+		}
+		el = prev
+	}
+	return best
+}
+
 func (sh *Shard) lruPeekHead() (key uint64, val *model.Entry, ok bool) {
 	if !sh.lruOn || sh.lru == nil {
 		return 0, nil, false
@@ -153,19 +201,19 @@ func (sh *Shard) lruPeekHeadK(k int, chooseFn func(*model.Entry) bool) (v *model
 
 	e := sh.lru.Front()
 	for i := 0; i < k && e != nil; i, e = i+1, e.Next() {
-		key := e.Value.(uint64)
-		vv, ok2 := sh.items[key]
-		if !ok2 {
-			continue
-		}
-		if chooseFn(vv) {
-			return vv, true
+		for vv := sh.items[e.Value.(uint64)]; vv != nil; vv = vv.Next() {
+			if chooseFn(vv) {
+				return vv, true
+			}
 		}
 	}
 	return nil, false
 }
 
 func (sh *Shard) lruPeekTailK(k int, chooseFn func(*model.Entry) bool) (v *model.Entry, ok bool) {
+	if sh.win != nil && k > 0 {
+		return sh.windowPeekTailK(k, chooseFn)
+	}
 	if !sh.lruOn || sh.lru == nil || k <= 0 {
 		return nil, false
 	}
@@ -174,13 +222,10 @@ func (sh *Shard) lruPeekTailK(k int, chooseFn func(*model.Entry) bool) (v *model
 
 	e := sh.lru.Back()
 	for i := 0; i < k && e != nil; i, e = i+1, e.Prev() {
-		key := e.Value.(uint64)
-		vv, ok2 := sh.items[key]
-		if !ok2 {
-			continue
-		}
-		if chooseFn(vv) {
-			return vv, true
+		for vv := sh.items[e.Value.(uint64)]; vv != nil; vv = vv.Next() {
+			if chooseFn(vv) {
+				return vv, true
+			}
 		}
 	}
 	return nil, false
@@ -1,9 +1,15 @@
 package db
 
 import (
+	"context"
+	"github.com/Borislavv/go-ash-cache/config"
 	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	"github.com/Borislavv/go-ash-cache/internal/shared/cachedtime"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
 	"github.com/stretchr/testify/require"
+	"strconv"
 	"testing"
+	"time"
 )
 
 // TestShard_EnableLRU_InitializesStructures initializes LRU structures.
@@ -189,6 +195,49 @@ func TestShard_LRUPopTail_RemovesAndReturns(t *testing.T) {
 	require.Nil(t, sh.lidx[1], "should remove from index")
 }
 
+// TestShard_LRUPopTail_WeighsPriority evicts the best victim among a few tail slots, not the tail itself.
+func TestShard_LRUPopTail_WeighsPriority(t *testing.T) {
+	cachedtime.RunIfEnabled(context.Background(), &config.Cache{}) // real time, so entries get idle
+	sh := NewShard(0)
+	sh.enableLRU()
+
+	for i, level := range []pubmodel.Priority{pubmodel.PriorityHigh, pubmodel.PriorityNormal} {
+		entry := model.NewEntry(model.NewKey(strconv.Itoa(i)), 0, false)
+		entry.SetPriority(level)
+		entry.SetPayload([]byte("data"))
+		sh.Set(uint64(i), entry)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	key, _, ok := sh.lruPopTail()
+	require.True(t, ok)
+	require.Equal(t, uint64(1), key, "the tail of high priority outlives the normal one")
+}
+
+// TestShard_LRUPopTail_SkipsPinnedTails evicts past any number of pinned tail slots and moves them to the front.
+func TestShard_LRUPopTail_SkipsPinnedTails(t *testing.T) {
+	sh := NewShard(0)
+	sh.enableLRU()
+
+	const pinned = 2 * pinnedSkips
+	for i := 0; i <= pinned; i++ {
+		entry := model.NewEntry(model.NewKey(strconv.Itoa(i)), 0, false)
+		if i < pinned {
+			entry.Pin()
+		}
+		entry.SetPayload([]byte("data"))
+		sh.Set(uint64(i), entry)
+	}
+
+	key, _, ok := sh.lruPopTail()
+	require.True(t, ok)
+	require.Equal(t, uint64(pinned), key)
+
+	_, _, ok = sh.lruPopTail()
+	require.False(t, ok, "pinned slots only")
+	require.Equal(t, pinned, sh.lru.Len())
+}
+
 // TestShard_LRUPeekHead_ReturnsMostRecent returns most recently used entry.
 func TestShard_LRUPeekHead_ReturnsMostRecent(t *testing.T) {
 	sh := NewShard(0)
@@ -10,6 +10,8 @@ import (
 	"context"
 	"github.com/Borislavv/go-ash-cache/config"
 	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
+	"math"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -31,18 +33,28 @@ type Map struct {
 	mem  int64  // aggregated payload size in bytes (atomic)
 	iter uint64 // round‑robin cursor for NextShard()
 
+	// Windowed mode (see window.go)
+	admitter       Admitter      // decides promotion from the window
+	windowFraction atomic.Uint64 // share of slots kept in the window (float64 bits)
+
+	pins pinBudget // weight charged by pinned entries (see pin.go)
+
 	shards [NumOfShards]*Shard
 }
 
 // NewMap creates the map and initializes shards. A lightweight gauge updater runs once per second and exits with ctx.
 func NewMap(ctx context.Context, cfg *config.Cache) *Map {
-	m := &Map{ctx: ctx, cfg: cfg}
+	m := &Map{ctx: ctx, cfg: cfg, admitter: admitAll{}}
+	m.pins.limit = cfg.DB.PinnedLimitBytes
 	for id := uint64(0); id < NumOfShards; id++ {
 		m.shards[id] = NewShard(id)
+		m.shards[id].pins = &m.pins
 	}
 
 	if cfg.Eviction.Enabled() && cfg.Eviction.IsListing {
 		m.useListingMode()
+	} else if cfg.Eviction.Enabled() && cfg.Eviction.IsWindowed {
+		m.useWindowedMode(cfg.Eviction.WindowFraction)
 	} else {
 		m.useSamplingMode()
 	}
@@ -60,14 +72,52 @@ func (m *Map) Set(key uint64, value *model.Entry) {
 	}
 }
 
-// Get reads a value.
+// SetIfAbsent inserts a value only if the key is not present yet and adjusts global counters.
+func (m *Map) SetIfAbsent(key uint64, value *model.Entry) (inserted bool) {
+	var bytesDelta int64
+	if bytesDelta, inserted = m.Shard(key).SetIfAbsent(key, value); inserted {
+		atomic.AddInt64(&m.mem, bytesDelta)
+		atomic.AddInt64(&m.len, 1)
+	}
+	return
+}
+
+// Get reads a value of the slot (the head of its collision chain).
 func (m *Map) Get(key uint64) (value *model.Entry, ok bool) {
 	return m.Shard(key).Get(key)
 }
 
-// Remove deletes a key and adjusts global counters.
+// GetKey reads the value of the full key.
+func (m *Map) GetKey(k *pubmodel.Key) (value *model.Entry, ok bool) {
+	return m.Shard(k.Value()).GetKey(k)
+}
+
+// Remove deletes a slot with all chained entries and adjusts global counters.
 func (m *Map) Remove(key uint64) (freedBytes int64, hit bool) {
-	freedBytes, hit = m.Shard(key).Remove(key)
+	sh := m.Shard(key)
+	sh.Lock()
+	freedBytes, removed := sh.removeSlotUnlocked(key)
+	sh.Unlock()
+	if removed > 0 {
+		atomic.AddInt64(&m.len, -removed)
+		atomic.AddInt64(&m.mem, -freedBytes)
+	}
+	return freedBytes, removed > 0
+}
+
+// RemoveKey deletes the value of the full key and adjusts global counters.
+func (m *Map) RemoveKey(k *pubmodel.Key) (freedBytes int64, hit bool) {
+	freedBytes, hit = m.Shard(k.Value()).RemoveKey(k)
+	if hit {
+		atomic.AddInt64(&m.len, -1)
+		atomic.AddInt64(&m.mem, -freedBytes)
+	}
+	return
+}
+
+// RemoveValue deletes a key only if it still holds the given value and adjusts global counters.
+func (m *Map) RemoveValue(key uint64, value *model.Entry) (freedBytes int64, hit bool) {
+	freedBytes, hit = m.Shard(key).RemoveValue(key, value)
 	if hit {
 		atomic.AddInt64(&m.len, -1)
 		atomic.AddInt64(&m.mem, -freedBytes)
@@ -137,6 +187,14 @@ func (m *Map) Shard(key uint64) *Shard { return m.shards[key&shardMask] }
 func (m *Map) NextShard() *Shard       { return m.shards[atomic.AddUint64(&m.iter, 1)&shardMask] }
 func (m *Map) Len() int64              { return atomic.LoadInt64(&m.len) }
 func (m *Map) Mem() int64              { return atomic.LoadInt64(&m.mem) }
+
+// Collisions returns the number of entries which were chained to a slot occupied by another key.
+func (m *Map) Collisions() (collisions int64) {
+	for _, sh := range m.shards {
+		collisions += sh.Collisions()
+	}
+	return
+}
 func (m *Map) AddMem(key uint64, delta int64) {
 	atomic.AddInt64(&m.mem, delta)
 	m.Shard(key).AddMem(delta)
@@ -156,8 +214,35 @@ func (m *Map) useSamplingMode() {
 	}
 }
 
+// useWindowedMode enables W-TinyLFU segments; fraction is the share of slots kept in the admission window.
+func (m *Map) useWindowedMode(fraction float64) {
+	m.mode = Windowed
+	m.SetWindowFraction(fraction)
+	for _, s := range m.shards {
+		s.disableLRU()
+		s.enableWindow(&m.windowFraction, &m.len)
+	}
+}
+
+// UseAdmitter sets the admission control which decides promotion from the window in Windowed mode
+// (admits everything by default). Must be called before the map is used.
+func (m *Map) UseAdmitter(admitter Admitter) {
+	m.admitter = admitter
+}
+
+// WindowFraction returns the share of slots kept in the admission window (Windowed mode).
+func (m *Map) WindowFraction() float64 {
+	return math.Float64frombits(m.windowFraction.Load())
+}
+
+// SetWindowFraction changes the share of slots kept in the admission window (Windowed mode).
+// Shards apply it lazily, on their next insert.
+func (m *Map) SetWindowFraction(fraction float64) {
+	m.windowFraction.Store(math.Float64bits(fraction))
+}
+
 func (m *Map) Touch(key uint64) {
-	if m.mode != Listing {
+	if m.mode == Sampling {
 		return
 	}
 	m.Shard(key).touchLRU(key)
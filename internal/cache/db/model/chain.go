@@ -0,0 +1,11 @@
+package model
+
+// Next returns the next entry of the collision chain. Must be called under the shard lock.
+func (e *Entry) Next() *Entry {
+	return e.next
+}
+
+// Link sets the next entry of the collision chain. Must be called under the exclusive shard lock.
+func (e *Entry) Link(next *Entry) {
+	e.next = next
+}
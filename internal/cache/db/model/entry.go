@@ -1,49 +1,73 @@
 package model
 
 import (
+	"context"
 	"github.com/Borislavv/go-ash-cache/model"
 	"sync/atomic"
 )
 
 type TTLCallback func(entry model.Item) ([]byte, error)
 
+// TTLCallbackCtx is a context-aware TTLCallback; ctx is canceled when the refresh is interrupted.
+type TTLCallbackCtx func(ctx context.Context, entry model.Item) ([]byte, error)
+
 type Entry struct {
-	key               *model.Key              // 64 bit xxh + hi + lo for manage collisions
-	ttl               int64                   // atomic: unix nano (used for refresh/remove entry)
-	isQueuedOnRefresh int32                   // atomic: int as bool; whether an item is queued on update
-	isRemoveOnTTL     int32                   // atomic: int as bool; whether an item should be removed on TTL exceeded
-	payload           *atomic.Pointer[[]byte] // atomic: payload ([]byte)
-	callback          TTLCallback
-	touchedAt         int64 // atomic: unix nano (used in LRU algo.)
-	updatedAt         int64 // atomic: unix nano (used for refresh entry)
+	key               *model.Key               // 64 bit xxh + hi + lo for manage collisions
+	ttl               int64                    // atomic: unix nano (used for refresh/remove entry)
+	isQueuedOnRefresh int32                    // atomic: int as bool; whether an item is queued on update
+	ttlMode           int32                    // atomic: model.TTLMode; what to do once TTL is exceeded
+	payload           *atomic.Pointer[content] // atomic: payload ([]byte) with its metadata (see meta.go)
+	callback          TTLCallbackCtx
+	touchedAt         int64      // atomic: unix nano (used in LRU algo.)
+	updatedAt         int64      // atomic: unix nano (used for refresh entry)
+	err               error      // tombstone: loader error replayed instead of payload (immutable once published)
+	failures          int32      // atomic: consecutive failed refreshes
+	retryAt           int64      // atomic: unix nano; no refresh attempt before (backoff after a failed refresh)
+	next              *Entry     // collision chain of the map slot (guarded by the shard lock)
+	version           uint64     // atomic: bumped on each stored payload (see version.go)
+	tags              []string   // immutable once a payload is stored (see tags.go)
+	rawKey            []byte     // original key; retained only if configured (immutable once published)
+	stagedMeta        model.Meta // metadata to be stored with the next payload (owned by the loader)
+	priority          int32      // atomic: model.Priority in eviction
+	pinned            int32      // atomic: int as bool; whether an item is exempt from eviction (see pin.go)
 }
 
 func NewEntry(key *model.Key, ttl int64, isRemoveOnTTL bool) *Entry {
 	e := &Entry{
 		key:     key,
 		ttl:     ttl,
-		payload: &atomic.Pointer[[]byte]{},
+		payload: &atomic.Pointer[content]{},
 	}
 	if isRemoveOnTTL {
-		e.isRemoveOnTTL = 1
+		e.ttlMode = int32(model.TTLModeRemove)
 	}
 	return e
 }
 
 func (e *Entry) OnTTL() ([]byte, error) {
-	return e.callback(e)
+	return e.callback(context.Background(), e)
+}
+
+func (e *Entry) OnTTLCtx(ctx context.Context) ([]byte, error) {
+	return e.callback(ctx, e)
 }
 
 func (e *Entry) Update() error {
-	payload, err := e.callback(e)
+	payload, err := e.callback(context.Background(), e)
 	if err != nil {
 		return err
 	}
-	e.payload.Store(&payload)
+	e.payload.Store(e.newContent(payload))
+	e.bumpVersion()
 	return nil
 }
 
 // SetCallback is NOT CONCURRENT SAFE!
 func (e *Entry) SetCallback(callback TTLCallback) {
+	e.callback = func(_ context.Context, entry model.Item) ([]byte, error) { return callback(entry) }
+}
+
+// SetCallbackCtx is NOT CONCURRENT SAFE!
+func (e *Entry) SetCallbackCtx(callback TTLCallbackCtx) {
 	e.callback = callback
 }
@@ -6,11 +6,12 @@ import (
 	"github.com/Borislavv/go-ash-cache/internal/shared/random"
 	"math"
 	"sync/atomic"
+	"time"
 )
 
 // IsExpired - checks that elapsed time greater than TTL.
 func (e *Entry) IsExpired(cfg *config.Cache) bool {
-	if e == nil {
+	if e == nil || e.IsBackingOff() {
 		return false
 	}
 
@@ -21,6 +22,21 @@ func (e *Entry) IsExpired(cfg *config.Cache) bool {
 	return e.isExpired()
 }
 
+// IsDueOnTTL reports whether the lifetimer has to handle the entry: it is expired and not kept stale,
+// or it is beyond lifetime.max_stale.
+func (e *Entry) IsDueOnTTL(cfg *config.Cache) bool {
+	return (!e.IsKeptStale() && e.IsExpired(cfg)) || e.IsBeyondMaxStale(cfg)
+}
+
+// IsBeyondMaxStale reports whether the entry is refreshed on TTL and its payload was stored
+// more than lifetime.max_stale ago (unlike IsExpired, regardless of backoff).
+func (e *Entry) IsBeyondMaxStale(cfg *config.Cache) bool {
+	if e == nil || !cfg.Lifetime.Enabled() || cfg.Lifetime.MaxStale <= 0 || !e.IsRefreshByTTL() || e.IsNegative() {
+		return false
+	}
+	return cachedtime.UnixNano()-atomic.LoadInt64(&e.updatedAt) > cfg.Lifetime.MaxStale.Nanoseconds()
+}
+
 func (e *Entry) isExpired() bool {
 	if e == nil {
 		return false
@@ -30,8 +46,8 @@ func (e *Entry) isExpired() bool {
 		return false
 	}
 
-	// Time since the last successful refresh.
-	elapsed := cachedtime.UnixNano() - atomic.LoadInt64(&e.updatedAt)
+	// Time since the last successful refresh (the last access in sliding mode).
+	elapsed := cachedtime.UnixNano() - e.expiresFrom()
 	return elapsed > ttl
 }
 
@@ -47,8 +63,8 @@ func (e *Entry) isProbablyExpired(beta, coefficient float64) bool {
 	}
 
 	var ttl = float64(i64TTL)
-	// Time since the last successful refresh.
-	elapsed := cachedtime.UnixNano() - atomic.LoadInt64(&e.updatedAt)
+	// Time since the last successful refresh (the last access in sliding mode).
+	elapsed := cachedtime.UnixNano() - e.expiresFrom()
 	// Hard floor: do nothing until elapsed >= coefficient * ttl.
 	minStale := int64(ttl * coefficient)
 
@@ -62,6 +78,24 @@ func (e *Entry) isProbablyExpired(beta, coefficient float64) bool {
 	return random.Float64() < probability
 }
 
+// Staleness returns how long ago the TTL of the entry elapsed; it is not positive while the entry is fresh
+// or has no TTL.
+func (e *Entry) Staleness() time.Duration {
+	ttl := atomic.LoadInt64(&e.ttl)
+	if ttl == 0 {
+		return 0
+	}
+	return time.Duration(cachedtime.UnixNano() - e.expiresFrom() - ttl)
+}
+
+// expiresFrom returns unix nano the TTL counts from: the last access in sliding mode, the last stored payload otherwise.
+func (e *Entry) expiresFrom() int64 {
+	if e.IsSliding() {
+		return atomic.LoadInt64(&e.touchedAt)
+	}
+	return atomic.LoadInt64(&e.updatedAt)
+}
+
 func (e *Entry) EnqueueExpired() bool {
 	return atomic.CompareAndSwapInt32(&e.isQueuedOnRefresh, 0, 1)
 }
@@ -69,3 +103,30 @@ func (e *Entry) EnqueueExpired() bool {
 func (e *Entry) DequeueExpired() {
 	atomic.StoreInt32(&e.isQueuedOnRefresh, 0)
 }
+
+// SetExpiresAt sets the TTL so that the entry expires at t. It is meant for a loader (or an Option):
+// the TTL counts from now, since a payload is stored right after the loader returns.
+// A moment in the past expires the entry at once.
+func (e *Entry) SetExpiresAt(t time.Time) {
+	e.SetTTL(max(time.Duration(t.UnixNano()-cachedtime.UnixNano()), 1))
+}
+
+// ExpireAt changes the TTL of a stored entry so that it expires at t (counting from the last stored payload).
+// In sliding mode the TTL counts from the last access, so a later access postpones the expiry again.
+func (e *Entry) ExpireAt(t time.Time) {
+	atomic.StoreInt64(&e.ttl, max(t.UnixNano()-e.expiresFrom(), 1))
+}
+
+// Persist removes the TTL of the entry and reports whether it had one.
+func (e *Entry) Persist() (hadTTL bool) {
+	return atomic.SwapInt64(&e.ttl, 0) != 0
+}
+
+// RemainingTTL returns how long the entry has left before its TTL elapses (not below zero)
+// and false if the entry has no TTL.
+func (e *Entry) RemainingTTL() (remaining time.Duration, ok bool) {
+	if atomic.LoadInt64(&e.ttl) == 0 {
+		return 0, false
+	}
+	return max(-e.Staleness(), 0), true
+}
@@ -2,6 +2,8 @@ package model
 
 import (
 	"github.com/Borislavv/go-ash-cache/config"
+	"github.com/Borislavv/go-ash-cache/internal/shared/cachedtime"
+	"github.com/Borislavv/go-ash-cache/model"
 	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
@@ -118,3 +120,77 @@ func TestEntry_IsProbablyExpired_Stochastic(t *testing.T) {
 	result := entry.IsExpired(cfg)
 	require.IsType(t, false, result, "IsExpired should return bool")
 }
+
+// TestEntry_Staleness reports how long ago TTL elapsed.
+func TestEntry_Staleness(t *testing.T) {
+	require.Zero(t, NewEntry(NewKey("no-ttl"), 0, false).Staleness(), "entry without TTL is never stale")
+
+	entry := NewEntry(NewKey("test"), time.Hour.Nanoseconds(), false)
+	entry.SetPayload([]byte("data"))
+	require.LessOrEqual(t, entry.Staleness(), time.Duration(0))
+
+	entry.updatedAt -= (2 * time.Hour).Nanoseconds()
+	require.InDelta(t, float64(time.Hour), float64(entry.Staleness()), float64(time.Second))
+}
+
+// TestEntry_ExpireAt_Persist changes the TTL of a stored entry and reports what is left.
+func TestEntry_ExpireAt_Persist(t *testing.T) {
+	entry := NewEntry(NewKey("test"), time.Hour.Nanoseconds(), true)
+	entry.SetPayload([]byte("data"))
+
+	remaining, ok := entry.RemainingTTL()
+	require.True(t, ok)
+	require.InDelta(t, float64(time.Hour), float64(remaining), float64(time.Second))
+
+	entry.ExpireAt(cachedtime.Now().Add(time.Minute))
+	remaining, _ = entry.RemainingTTL()
+	require.InDelta(t, float64(time.Minute), float64(remaining), float64(time.Second))
+
+	entry.ExpireAt(cachedtime.Now().Add(-time.Minute))
+	remaining, ok = entry.RemainingTTL()
+	require.True(t, ok)
+	require.LessOrEqual(t, remaining, time.Nanosecond, "a moment in the past leaves the least TTL")
+
+	require.True(t, entry.Persist())
+	require.False(t, entry.Persist())
+	_, ok = entry.RemainingTTL()
+	require.False(t, ok)
+	require.False(t, entry.IsExpired(&config.Cache{}))
+}
+
+// TestEntry_Sliding_CountsFromLastAccess expires a sliding entry by its last access, not by its last payload.
+func TestEntry_Sliding_CountsFromLastAccess(t *testing.T) {
+	entry := NewEntry(NewKey("test"), time.Hour.Nanoseconds(), true)
+	entry.SetTTLMode(model.TTLModeSliding)
+	entry.SetPayload([]byte("data"))
+	require.True(t, entry.IsRemoveByTTL(), "a sliding entry is removed on TTL")
+
+	entry.updatedAt -= (2 * time.Hour).Nanoseconds()
+	require.False(t, entry.isExpired(), "a recently accessed entry is kept however old its payload is")
+
+	entry.touchedAt -= (2 * time.Hour).Nanoseconds()
+	require.True(t, entry.isExpired(), "an idle entry expires")
+	require.InDelta(t, float64(time.Hour), float64(entry.Staleness()), float64(time.Second))
+
+	entry.RenewTouchedAt()
+	require.False(t, entry.isExpired(), "an access renews the TTL")
+}
+
+// TestEntry_IsBeyondMaxStale bounds the age of a refreshed payload regardless of backoff.
+func TestEntry_IsBeyondMaxStale(t *testing.T) {
+	cfg := &config.Cache{Lifetime: &config.LifetimerCfg{OnTTL: config.TTLModeRefresh, MaxStale: time.Hour}}
+	cfg.AdjustConfig()
+
+	entry := NewEntry(NewKey("test"), time.Minute.Nanoseconds(), false)
+	entry.SetPayload([]byte("data"))
+	require.False(t, entry.IsBeyondMaxStale(cfg))
+
+	entry.updatedAt -= (2 * time.Hour).Nanoseconds()
+	require.True(t, entry.IsBeyondMaxStale(cfg))
+	require.True(t, entry.IsDueOnTTL(cfg))
+
+	entry.SetTTLMode(model.TTLModeRemove)
+	require.False(t, entry.IsBeyondMaxStale(cfg), "only entries refreshed on TTL are bounded")
+
+	require.False(t, entry.IsBeyondMaxStale(&config.Cache{}), "no limit without lifetime")
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"github.com/Borislavv/go-ash-cache/internal/shared/cachedtime"
+	"sync/atomic"
+	"time"
+)
+
+// maxBackoffShift bounds the exponent of the refresh backoff to avoid overflow.
+const maxBackoffShift = 30
+
+// Failures returns the number of consecutive failed refreshes of the entry.
+func (e *Entry) Failures() int32 {
+	return atomic.LoadInt32(&e.failures)
+}
+
+// OnRefreshFailed counts a failed refresh and pushes the next attempt out by an exponential backoff:
+// backoff * 2^(failures-1), at most maxBackoff.
+func (e *Entry) OnRefreshFailed(backoff, maxBackoff time.Duration) (failures int32) {
+	failures = atomic.AddInt32(&e.failures, 1)
+	delay := backoff << min(failures-1, maxBackoffShift)
+	if delay <= 0 || delay > maxBackoff {
+		delay = maxBackoff
+	}
+	atomic.StoreInt64(&e.retryAt, cachedtime.UnixNano()+delay.Nanoseconds())
+	return failures
+}
+
+// ResetFailures forgets failed refreshes (called once a payload is stored).
+func (e *Entry) ResetFailures() {
+	atomic.StoreInt32(&e.failures, 0)
+	atomic.StoreInt64(&e.retryAt, 0)
+}
+
+// IsBackingOff reports whether a next refresh attempt of the entry is delayed after a failed one.
+func (e *Entry) IsBackingOff() bool {
+	retryAt := atomic.LoadInt64(&e.retryAt)
+	return retryAt != 0 && cachedtime.UnixNano() < retryAt
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"github.com/Borislavv/go-ash-cache/internal/shared/cachedtime"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// TestEntry_OnRefreshFailed_BacksOffExponentially doubles the retry delay up to the cap.
+func TestEntry_OnRefreshFailed_BacksOffExponentially(t *testing.T) {
+	entry := NewEntry(NewKey("test"), time.Second.Nanoseconds(), false)
+	entry.SetPayload([]byte("data"))
+	require.False(t, entry.IsBackingOff())
+
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		entry.OnRefreshFailed(time.Second, 5*time.Second)
+		delay := time.Duration(entry.retryAt - cachedtime.UnixNano())
+		require.InDelta(t, float64(want), float64(delay), float64(100*time.Millisecond), "failure %d", i+1)
+	}
+	require.Equal(t, int32(5), entry.Failures())
+	require.True(t, entry.IsBackingOff())
+	require.False(t, entry.IsExpired(nil), "a backed off entry is not picked for refresh")
+
+	entry.ResetFailures()
+	require.Zero(t, entry.Failures())
+	require.False(t, entry.IsBackingOff())
+}
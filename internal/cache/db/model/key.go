@@ -8,6 +8,18 @@ import (
 )
 
 func NewKey(key string) *model.Key {
+	k := buildKey(unsafe.Slice(unsafe.StringData(key), len(key)))
+	return &k
+}
+
+// KeyOf hashes the key bytes into a Key value. Unlike NewKey it does not allocate
+// as long as the caller keeps the value (and pointers to it) on the stack.
+func KeyOf(key []byte) model.Key {
+	return buildKey(key)
+}
+
+// KeyOfString is the string counterpart of KeyOf.
+func KeyOfString(key string) model.Key {
 	return buildKey(unsafe.Slice(unsafe.StringData(key), len(key)))
 }
 
@@ -20,7 +32,7 @@ func (e *Entry) Key() *model.Key {
 	return e.key
 }
 
-func buildKey(key []byte) *model.Key {
+func buildKey(key []byte) model.Key {
 	// acquire reusable hasher
 	hasher := hasherPool.Get().(*xxh3.Hasher)
 	hasher.Reset()
@@ -31,7 +43,7 @@ func buildKey(key []byte) *model.Key {
 	u128 := hasher.Sum128()
 
 	// calculate map key
-	k := model.NewKey(hasher.Sum64(), u128.Hi, u128.Lo)
+	k := *model.NewKey(hasher.Sum64(), u128.Hi, u128.Lo)
 
 	// release hasher after use
 	hasherPool.Put(hasher)
@@ -44,5 +56,15 @@ func (e *Entry) setUpNewKey(data []byte) {
 		// already exists
 		return
 	}
-	e.key = buildKey(data)
+	k := buildKey(data)
+	e.key = &k
 }
+
+// SetRawKey keeps a copy of the original key bytes. NOT CONCURRENT SAFE: call before the entry is published.
+func (e *Entry) SetRawKey(raw []byte) {
+	e.rawKey = append(make([]byte, 0, len(raw)), raw...)
+}
+
+// RawKey returns the original key bytes if they are retained (see config.DBCfg.RetainKeys), nil otherwise.
+// The result must not be modified.
+func (e *Entry) RawKey() []byte { return e.rawKey }
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"github.com/Borislavv/go-ash-cache/model"
+	"unsafe"
+)
+
+// metaPairWeight approximates the memory a metadata pair takes besides its strings (headers and map overhead).
+const metaPairWeight = int64(2*unsafe.Sizeof("")) + 16
+
+// content is a payload with its metadata. Both are replaced at once by storing a new content.
+type content struct {
+	payload []byte
+	meta    model.Meta
+}
+
+func (c *content) weight() (weight int64) {
+	if c == nil {
+		return 0
+	}
+	for k, v := range c.meta {
+		weight += int64(len(k)+len(v)) + metaPairWeight
+	}
+	return weight + int64(cap(c.payload))
+}
+
+// SetMeta stages a metadata pair to be stored along with the next payload: the one returned by a loader
+// (on load and on refresh) or written by Set with an Option. A stored payload replaces the metadata as a whole.
+// NOT CONCURRENT SAFE: only the loader (or Option) of the entry may call it.
+func (e *Entry) SetMeta(key, value string) {
+	if e.stagedMeta == nil {
+		e.stagedMeta = make(model.Meta, 1)
+	}
+	e.stagedMeta[key] = value
+}
+
+// Meta returns metadata of the stored payload. The result must not be modified.
+func (e *Entry) Meta() model.Meta {
+	if c := e.payload.Load(); c != nil {
+		return c.meta
+	}
+	return nil
+}
+
+// PayloadWithMeta returns the payload with the metadata stored along with it.
+func (e *Entry) PayloadWithMeta() ([]byte, model.Meta) {
+	if c := e.payload.Load(); c != nil {
+		return c.payload, c.meta
+	}
+	return nil, nil
+}
+
+// newContent wraps the payload with the staged metadata and resets the stage.
+func (e *Entry) newContent(p []byte) *content {
+	c := &content{payload: p, meta: e.stagedMeta}
+	e.stagedMeta = nil
+	return c
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"github.com/Borislavv/go-ash-cache/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestEntry_Meta_StoredWithPayload stores staged metadata along with the payload and counts it in the weight.
+func TestEntry_Meta_StoredWithPayload(t *testing.T) {
+	plain := NewEntry(NewKey("test"), 0, false)
+	plain.SetPayload([]byte("data"))
+
+	entry := NewEntry(NewKey("test"), 0, false)
+	entry.SetMeta("Content-Type", "application/json")
+	require.Nil(t, entry.Meta(), "staged metadata is not visible before a payload is stored")
+	entry.SetPayload([]byte("data"))
+
+	payload, meta := entry.PayloadWithMeta()
+	require.Equal(t, []byte("data"), payload)
+	require.Equal(t, model.Meta{"Content-Type": "application/json"}, meta)
+	require.Greater(t, entry.Weight(), plain.Weight())
+	require.False(t, entry.IsTheSamePayload(plain))
+
+	// a computed payload keeps metadata
+	entry.ReplacePayload([]byte("computed"))
+	require.Equal(t, meta, entry.Meta())
+
+	// a refresh replaces metadata as a whole
+	before := entry.Weight()
+	entry.SetMeta("ETag", "v2")
+	weightDiff := entry.RefreshPayload([]byte("data"))
+	require.Equal(t, model.Meta{"ETag": "v2"}, entry.Meta())
+	require.Equal(t, before+weightDiff, entry.Weight())
+}
@@ -0,0 +1,23 @@
+package model
+
+// SetNegative turns the entry into a tombstone which replays err instead of a payload.
+// NOT CONCURRENT SAFE! Must be called before the entry is published.
+func (e *Entry) SetNegative(err error) {
+	e.err = err
+}
+
+// IsNegative reports whether the entry is a tombstone.
+func (e *Entry) IsNegative() bool {
+	return e.err != nil
+}
+
+// Err returns the loader error replayed by a tombstone (nil for regular entries).
+func (e *Entry) Err() error {
+	return e.err
+}
+
+// IsTombstoneExpired reports whether a tombstone outlived its TTL and must not be replayed anymore.
+// Unlike IsExpired, it is not stochastic: a tombstone is never replayed beyond its TTL.
+func (e *Entry) IsTombstoneExpired() bool {
+	return e.IsNegative() && e.isExpired()
+}
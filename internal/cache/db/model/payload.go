@@ -3,22 +3,29 @@ package model
 import (
 	"github.com/Borislavv/go-ash-cache/internal/shared/bytes"
 	"github.com/Borislavv/go-ash-cache/internal/shared/cachedtime"
+	"maps"
 	"sync/atomic"
 	"unsafe"
 )
 
-func (e *Entry) Weight() int64 { return int64(unsafe.Sizeof(*e)) + int64(cap(e.PayloadBytes())) }
+func (e *Entry) Weight() int64 {
+	return int64(unsafe.Sizeof(*e)) + e.payload.Load().weight() + int64(cap(e.rawKey)) + e.tagsWeight()
+}
 
 func (e *Entry) PayloadBytes() []byte {
-	if ptr := e.payload.Load(); ptr != nil {
-		return *ptr
+	if c := e.payload.Load(); c != nil {
+		return c.payload
 	}
 	return nil
 }
 
+// IsTheSamePayload reports whether both entries hold equal payloads with equal metadata.
 func (e *Entry) IsTheSamePayload(another *Entry) bool {
-	a := e.PayloadBytes()
-	b := another.PayloadBytes()
+	a, aMeta := e.PayloadWithMeta()
+	b, bMeta := another.PayloadWithMeta()
+	if !maps.Equal(aMeta, bMeta) {
+		return false
+	}
 	if a == nil {
 		return b == nil
 	}
@@ -28,10 +35,12 @@ func (e *Entry) IsTheSamePayload(another *Entry) bool {
 	return false
 }
 
+// SwapPayloads stores the payload (with metadata) of another; tags of the entry are kept.
 func (e *Entry) SwapPayloads(another *Entry) (weightDiff int64) {
-	newWeight := another.Weight()
-	oldWeight := e.Weight()
-	e.payload.Swap(another.payload.Load())
+	in := another.payload.Load()
+	newWeight := in.weight()
+	oldWeight := e.payload.Swap(in).weight()
+	e.bumpVersion()
 	return newWeight - oldWeight
 }
 
@@ -40,6 +49,40 @@ func (e *Entry) SetPayload(p []byte) {
 	atomic.StoreInt64(&e.touchedAt, now)
 	atomic.StoreInt64(&e.updatedAt, now)
 	atomic.StoreInt32(&e.isQueuedOnRefresh, 0)
+	e.ResetFailures()
 	e.setUpNewKey(p)
-	e.payload.Store(&p)
+	e.payload.Store(e.newContent(p))
+	e.bumpVersion()
+}
+
+// RefreshPayload stores a refreshed payload and returns the weight diff for memory accounting.
+// Unlike SetPayload, it doesn't renew touchedAt: a background refresh is not an access.
+func (e *Entry) RefreshPayload(p []byte) (weightDiff int64) {
+	in := e.newContent(p)
+	old := e.payload.Swap(in)
+	e.onContentStored()
+	return in.weight() - old.weight()
+}
+
+// ReplacePayload stores a payload computed from the current one (see Cache.Compute) and returns the weight diff.
+// Unlike RefreshPayload, it keeps metadata of the current payload.
+func (e *Entry) ReplacePayload(p []byte) (weightDiff int64) {
+	for {
+		cur := e.payload.Load()
+		in := &content{payload: p}
+		if cur != nil {
+			in.meta = cur.meta
+		}
+		if e.payload.CompareAndSwap(cur, in) {
+			e.onContentStored()
+			return in.weight() - cur.weight()
+		}
+	}
+}
+
+func (e *Entry) onContentStored() {
+	atomic.StoreInt64(&e.updatedAt, cachedtime.UnixNano())
+	atomic.StoreInt32(&e.isQueuedOnRefresh, 0)
+	e.ResetFailures()
+	e.bumpVersion()
 }
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"github.com/Borislavv/go-ash-cache/model"
+	"sync/atomic"
+)
+
+// SetPriority sets the eviction priority of the entry; it may be changed at any time (e.g. on refresh).
+func (e *Entry) SetPriority(level model.Priority) {
+	atomic.StoreInt32(&e.priority, int32(level))
+}
+
+func (e *Entry) Priority() model.Priority {
+	return model.Priority(atomic.LoadInt32(&e.priority))
+}
+
+// Pin exempts the entry from eviction. Like tags, pinned entries are indexed by the shard on insertion,
+// so a call made once a payload is stored (e.g. by a loader on refresh) is ignored; see SetPinned.
+func (e *Entry) Pin() {
+	if e.Version() != 0 {
+		return
+	}
+	atomic.StoreInt32(&e.pinned, 1)
+}
+
+// SetPinned pins or unpins the entry and reports whether it changed. A stored entry must be
+// (un)pinned under the shard lock along with its index (see db.Map.Pin).
+func (e *Entry) SetPinned(pinned bool) (changed bool) {
+	var from, to int32 = 1, 0
+	if pinned {
+		from, to = 0, 1
+	}
+	return atomic.CompareAndSwapInt32(&e.pinned, from, to)
+}
+
+func (e *Entry) IsPinned() bool {
+	return atomic.LoadInt32(&e.pinned) == 1
+}
@@ -0,0 +1,54 @@
+package model
+
+import "unsafe"
+
+// tagRefWeight approximates the memory a tag of an entry takes in the tag index of its shard
+// (the string header and the set membership). It is a part of the entry weight, so the index
+// is accounted against the storage size along with payloads.
+const tagRefWeight = int64(unsafe.Sizeof("")) + 32
+
+// AddTags attaches tags to the entry. Tags are immutable once a payload is stored: the shard indexes them
+// on insertion, so a call made later (e.g. by a loader on refresh) is ignored.
+func (e *Entry) AddTags(tags ...string) {
+	if e.Version() != 0 {
+		return
+	}
+	for _, tag := range tags {
+		if !e.HasTag(tag) {
+			e.tags = append(e.tags, tag)
+		}
+	}
+}
+
+// Tags returns tags of the entry. The result must not be modified.
+func (e *Entry) Tags() []string { return e.tags }
+
+// HasTag reports whether the entry is tagged by the tag.
+func (e *Entry) HasTag(tag string) bool {
+	for _, t := range e.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTheSameTags reports whether both entries are tagged by the same set of tags.
+func (e *Entry) IsTheSameTags(another *Entry) bool {
+	if len(e.tags) != len(another.tags) {
+		return false
+	}
+	for _, tag := range e.tags {
+		if !another.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Entry) tagsWeight() (weight int64) {
+	for _, tag := range e.tags {
+		weight += int64(len(tag)) + tagRefWeight
+	}
+	return weight
+}
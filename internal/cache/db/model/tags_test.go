@@ -0,0 +1,24 @@
+package model
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestEntry_Tags_FrozenOnceStored ignores tags added after a payload is stored and counts tags in the weight.
+func TestEntry_Tags_FrozenOnceStored(t *testing.T) {
+	entry := NewEntry(NewKey("test"), 0, false)
+	entry.SetPayload([]byte("data"))
+	untagged := entry.Weight()
+
+	tagged := NewEntry(NewKey("test"), 0, false)
+	tagged.AddTags("a", "b", "a")
+	tagged.SetPayload([]byte("data"))
+	require.Equal(t, []string{"a", "b"}, tagged.Tags())
+	require.Greater(t, tagged.Weight(), untagged)
+
+	// e.g. by a loader on refresh
+	tagged.AddTags("c")
+	require.Equal(t, []string{"a", "b"}, tagged.Tags())
+	require.False(t, tagged.IsTheSameTags(entry))
+}
@@ -7,26 +7,53 @@ import (
 	"time"
 )
 
-var trueI32 = int32(1)
-
 func (e *Entry) SetTTL(ttl time.Duration) {
 	atomic.StoreInt64(&e.ttl, ttl.Nanoseconds())
 }
 
+func (e *Entry) TTL() time.Duration {
+	return time.Duration(atomic.LoadInt64(&e.ttl))
+}
+
 func (e *Entry) SetTTLMode(mode model.TTLMode) {
-	atomic.StoreInt32(&e.isRemoveOnTTL, int32(mode))
+	atomic.StoreInt32(&e.ttlMode, int32(mode))
 }
 
+func (e *Entry) TTLMode() model.TTLMode {
+	return model.TTLMode(atomic.LoadInt32(&e.ttlMode))
+}
+
+// IsRemoveByTTL reports whether the entry is removed (rather than refreshed) once its TTL is exceeded.
 func (e *Entry) IsRemoveByTTL() bool {
-	return atomic.LoadInt32(&e.isRemoveOnTTL) == trueI32
+	mode := e.TTLMode()
+	return mode == model.TTLModeRemove || mode == model.TTLModeSliding
+}
+
+// IsRefreshByTTL reports whether the entry is refreshed by its callback once its TTL is exceeded.
+func (e *Entry) IsRefreshByTTL() bool {
+	return e.TTLMode() == model.TTLModeRefresh
+}
+
+// IsKeptStale reports whether the entry is neither removed nor refreshed once its TTL is exceeded.
+func (e *Entry) IsKeptStale() bool {
+	return e.TTLMode() == model.TTLModeStale
+}
+
+// IsSliding reports whether the TTL of the entry counts from its last access.
+func (e *Entry) IsSliding() bool {
+	return e.TTLMode() == model.TTLModeSliding
 }
 
 func (e *Entry) UpdatedAt() int64 {
 	return atomic.LoadInt64(&e.updatedAt)
 }
 
+// RenewTouchedAt is called on each access. The cached clock ticks coarsely, so hot entries
+// mostly skip the store and don't bounce the cache line between cores.
 func (e *Entry) RenewTouchedAt() {
-	atomic.StoreInt64(&e.touchedAt, cachedtime.UnixNano())
+	if now := cachedtime.UnixNano(); atomic.LoadInt64(&e.touchedAt) != now {
+		atomic.StoreInt64(&e.touchedAt, now)
+	}
 }
 
 func (e *Entry) TouchedAt() int64 {
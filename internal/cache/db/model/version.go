@@ -0,0 +1,18 @@
+package model
+
+import "sync/atomic"
+
+// versions is the source of entry versions. It is shared by all entries, so a version never repeats:
+// a key removed and stored again can't match a version read before the removal.
+var versions uint64
+
+// Version returns the version of the stored payload; 0 means no payload was stored yet.
+// The version is bumped after the payload is stored, so a version read before the payload
+// is never newer than the payload.
+func (e *Entry) Version() uint64 {
+	return atomic.LoadUint64(&e.version)
+}
+
+func (e *Entry) bumpVersion() {
+	atomic.StoreUint64(&e.version, atomic.AddUint64(&versions, 1))
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestEntry_Version_BumpedOnEveryPayload increases the version on each stored payload and never repeats it across entries.
+func TestEntry_Version_BumpedOnEveryPayload(t *testing.T) {
+	entry := NewEntry(NewKey("test"), 0, false)
+	require.Zero(t, entry.Version())
+
+	entry.SetPayload([]byte("data"))
+	v1 := entry.Version()
+	require.NotZero(t, v1)
+
+	entry.RefreshPayload([]byte("refreshed"))
+	v2 := entry.Version()
+	require.Greater(t, v2, v1)
+
+	another := NewEntry(NewKey("test"), 0, false)
+	another.SetPayload([]byte("swapped"))
+	entry.SwapPayloads(another)
+	require.Greater(t, entry.Version(), v2)
+
+	// the same key stored again by a new entry gets a new version
+	again := NewEntry(NewKey("test"), 0, false)
+	again.SetPayload([]byte("data"))
+	require.Greater(t, again.Version(), entry.Version())
+}
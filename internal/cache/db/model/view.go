@@ -0,0 +1,20 @@
+package model
+
+import "github.com/Borislavv/go-ash-cache/model"
+
+// View takes a read-only snapshot of the entry. Fields are loaded one by one, so a concurrent update
+// may be seen partially; the version is loaded first and is never newer than the payload.
+func (e *Entry) View() model.ItemView {
+	version := e.Version()
+	payload, meta := e.PayloadWithMeta()
+	return model.ItemView{
+		Payload:   payload,
+		Meta:      meta,
+		RawKey:    e.rawKey,
+		Tags:      e.tags,
+		TTL:       e.TTL(),
+		UpdatedAt: e.UpdatedAt(),
+		TouchedAt: e.TouchedAt(),
+		Version:   version,
+	}
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
+	"math"
+	"sync/atomic"
+)
+
+// Like tags, each shard indexes its own pinned entries, along with the weight each one is charged to the pinned
+// budget of the map (db.pinned_fraction of the cache size). An entry is charged once it's linked pinned (or pinned
+// in place) and released once unlinked (or unpinned); the budget is reserved by CAS, so concurrent pins never
+// overshoot it together, and a pin which doesn't fit is rolled back. Eviction skips pinned entries by their flag.
+
+// pinBudget is the pinned weight of a map shared by its shards.
+type pinBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// reserve charges bytes to the budget unless they exceed its limit.
+func (b *pinBudget) reserve(bytes int64) bool {
+	for {
+		used := b.used.Load()
+		if used+bytes > b.limit {
+			return false
+		}
+		if b.used.CompareAndSwap(used, used+bytes) {
+			return true
+		}
+	}
+}
+
+func (b *pinBudget) release(bytes int64) { b.used.Add(-bytes) }
+
+// unlimitedPins is the budget of a standalone shard (see NewShard).
+func unlimitedPins() *pinBudget { return &pinBudget{limit: math.MaxInt64} }
+
+// Pin pins the stored entry of the key and reports whether it's pinned: false if the key is absent
+// or its weight doesn't fit into the pinned budget.
+func (m *Map) Pin(k *pubmodel.Key) (pinned bool) {
+	sh := m.Shard(k.Value())
+	sh.Lock()
+	defer sh.Unlock()
+	entry, found := sh.findUnlocked(k.Value(), k)
+	return found && sh.pinInPlaceUnlocked(entry)
+}
+
+// Unpin unpins the stored entry of the key and reports whether it was pinned.
+func (m *Map) Unpin(k *pubmodel.Key) (unpinned bool) {
+	sh := m.Shard(k.Value())
+	sh.Lock()
+	defer sh.Unlock()
+	entry, found := sh.findUnlocked(k.Value(), k)
+	if found && entry.SetPinned(false) {
+		sh.unpinUnlocked(entry)
+		return true
+	}
+	return false
+}
+
+// Repin syncs the pinned budget with a stored entry updated in place: it pins the entry if pin is set
+// (a write of a pinned item; see Pin) and recharges a pinned one by its current weight. A pinned payload
+// which grew in place is charged even over the limit, further pins wait for room.
+func (m *Map) Repin(entry *model.Entry, pin bool) {
+	key := entry.Key().Value()
+	sh := m.Shard(key)
+	sh.Lock()
+	defer sh.Unlock()
+	if cur, found := sh.findUnlocked(key, entry.Key()); !found || cur != entry {
+		return // removed or replaced meanwhile
+	}
+	if pin {
+		entry.SetPinned(true)
+	}
+	sh.syncPinnedUnlocked(entry)
+}
+
+// PinnedMem returns the total weight charged by pinned entries.
+func (m *Map) PinnedMem() int64 {
+	return m.pins.used.Load()
+}
+
+// pinInPlaceUnlocked pins a linked entry and reports whether it's pinned.
+func (sh *Shard) pinInPlaceUnlocked(entry *model.Entry) bool {
+	if entry.SetPinned(true) {
+		sh.pinUnlocked(entry)
+	}
+	return entry.IsPinned()
+}
+
+// syncPinnedUnlocked brings the index in line with a linked entry updated in place: an entry pinned meanwhile
+// is charged (or unpinned if it doesn't fit), a pinned one is recharged by its current weight.
+func (sh *Shard) syncPinnedUnlocked(entry *model.Entry) {
+	charged, indexed := sh.pinned[entry]
+	if !indexed {
+		sh.pinUnlocked(entry)
+		return
+	}
+	weight := entry.Weight()
+	sh.pinned[entry] = weight
+	sh.pinnedMem += weight - charged
+	sh.pins.used.Add(weight - charged)
+}
+
+// pinUnlocked charges a linked pinned entry to the budget and indexes it; an entry which doesn't fit is unpinned.
+func (sh *Shard) pinUnlocked(entry *model.Entry) {
+	if !entry.IsPinned() {
+		return
+	}
+	weight := entry.Weight()
+	if !sh.pins.reserve(weight) {
+		entry.SetPinned(false)
+		return
+	}
+	if sh.pinned == nil {
+		sh.pinned = make(map[*model.Entry]int64)
+	}
+	sh.pinned[entry] = weight
+	sh.pinnedMem += weight
+}
+
+// unpinUnlocked drops an unlinked (or unpinned) entry from the index and releases its charge.
+func (sh *Shard) unpinUnlocked(entry *model.Entry) {
+	if charged, ok := sh.pinned[entry]; ok {
+		delete(sh.pinned, entry)
+		sh.pinnedMem -= charged
+		sh.pins.release(charged)
+	}
+}
+
+// isChainPinned reports whether any entry of the chain is pinned: a slot is evicted with its whole chain.
+func isChainPinned(head *model.Entry) bool {
+	for e := head; e != nil; e = e.Next() {
+		if e.IsPinned() {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"github.com/Borislavv/go-ash-cache/config"
 	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
 	"sync/atomic"
 )
@@ -24,15 +25,9 @@ func (m *Map) peekExpiredByQueues() (*model.Entry, bool) {
 	for i := 0; i < NumOfShards; i++ {
 		sh := m.shards[(start+i)&shardMask]
 		if k, ok := sh.DequeueExpired(); ok {
-			if v, ok2 := sh.Get(k); ok2 { // under RLock
-				// double-check freshness
-				if v.IsExpired(m.cfg) {
-					// caller refreshes; the flag will be cleared after success
-					return v, true
-				} else {
-					// not ready; reset flag
-					v.DequeueExpired()
-				}
+			if v, ok2 := sh.getExpired(k, m.cfg); ok2 {
+				// caller refreshes; the flag will be cleared after success
+				return v, true
 			}
 		}
 	}
@@ -56,24 +51,41 @@ loop:
 			continue
 		}
 
-		for _, entry := range sh.items {
-			if seen >= maxSeen || hitSeen >= sample {
-				sh.RUnlock()
-				break loop
-			}
-			if entry.IsExpired(m.cfg) {
-				hitSeen++
-				if !set {
-					best = entry
-					set = true
-				} else if best != nil && best.UpdatedAt() > entry.UpdatedAt() {
-					best = entry
+		for _, head := range sh.items {
+			for entry := head; entry != nil; entry = entry.Next() {
+				if seen >= maxSeen || hitSeen >= sample {
+					sh.RUnlock()
+					break loop
 				}
+				if entry.IsDueOnTTL(m.cfg) {
+					hitSeen++
+					if !set {
+						best = entry
+						set = true
+					} else if best != nil && best.UpdatedAt() > entry.UpdatedAt() {
+						best = entry
+					}
+				}
+				seen++
 			}
-			seen++
 		}
 		sh.RUnlock()
 	}
 
 	return best, set
 }
+
+// getExpired double-checks freshness of entries of a dequeued slot under RLock: returns an expired one
+// and resets the queued flag of the others (not ready).
+func (sh *Shard) getExpired(key uint64, cfg *config.Cache) (expired *model.Entry, found bool) {
+	sh.RLock()
+	defer sh.RUnlock()
+	for v := sh.items[key]; v != nil; v = v.Next() {
+		if !found && v.IsDueOnTTL(cfg) {
+			expired, found = v, true
+		} else {
+			v.DequeueExpired()
+		}
+	}
+	return
+}
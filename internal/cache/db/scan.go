@@ -0,0 +1,86 @@
+package db
+
+import (
+	"bytes"
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	"sync/atomic"
+)
+
+// Both walks below visit shards one by one like WalkShards does and lock a single shard at a time,
+// so concurrent access to other shards is not blocked. They see original keys only
+// if they are retained (see config.DBCfg.RetainKeys).
+
+// RemovePrefix removes entries whose original keys start with the prefix and adjusts global counters.
+func (m *Map) RemovePrefix(prefix []byte) (freedBytes, removed int64) {
+	for _, sh := range m.shards {
+		if m.ctx.Err() != nil {
+			return
+		}
+		freed, n := sh.RemovePrefix(prefix)
+		if n > 0 {
+			atomic.AddInt64(&m.mem, -freed)
+			atomic.AddInt64(&m.len, -n)
+			freedBytes += freed
+			removed += n
+		}
+	}
+	return
+}
+
+// RemovePrefix removes entries of the shard whose original keys start with the prefix.
+func (sh *Shard) RemovePrefix(prefix []byte) (freedBytes, removed int64) {
+	if sh.Len() == 0 {
+		return 0, 0
+	}
+	sh.Lock()
+	defer sh.Unlock()
+	for key, head := range sh.items {
+		for e := head; e != nil; {
+			next := e.Next() // unlinking resets the link
+			if raw := e.RawKey(); raw != nil && bytes.HasPrefix(raw, prefix) {
+				if freed, hit := sh.removeEntryUnlocked(key, e); hit {
+					freedBytes += freed
+					removed++
+				}
+			}
+			e = next
+		}
+	}
+	return
+}
+
+// Scan walks shards starting from the cursor (a shard index) and passes original keys of entries
+// to fn until at least count keys were accepted; shards are always walked as a whole.
+// Returns the cursor to continue from; 0 means the walk is complete.
+func (m *Map) Scan(cursor uint64, count int, fn func(entry *model.Entry) (accepted bool)) (next uint64) {
+	for idx := cursor; idx < NumOfShards; idx++ {
+		if m.ctx.Err() != nil {
+			return idx
+		}
+		count -= m.shards[idx].scan(fn)
+		if count <= 0 {
+			if idx+1 == NumOfShards {
+				return 0
+			}
+			return idx + 1
+		}
+	}
+	return 0
+}
+
+// scan passes entries of the shard with retained keys to fn under the shared lock.
+func (sh *Shard) scan(fn func(entry *model.Entry) (accepted bool)) (accepted int) {
+	if sh.Len() == 0 {
+		return 0
+	}
+	sh.RLock()
+	defer sh.RUnlock()
+	for _, head := range sh.items {
+		for e := head; e != nil; e = e.Next() {
+			if e.RawKey() != nil && fn(e) {
+				accepted++
+			}
+		}
+	}
+	return accepted
+}
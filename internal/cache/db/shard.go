@@ -19,24 +19,37 @@ const queueCap = 4096
 // It keeps per-shard counters read with atomics so global readers can avoid locks.
 type Shard struct {
 	sync.RWMutex
-	items map[uint64]*model.Entry
+	items map[uint64]*model.Entry // slot -> head of the collision chain (see chain.go)
 
-	id       uint64
-	mem      int64  // total payload weight in bytes (atomic)
-	len      int64  // number of items (atomic)
-	randIter uint64 // cheap pseudo-random offset & probes
+	id         uint64
+	mem        int64  // total payload weight in bytes (atomic)
+	len        int64  // number of items (atomic)
+	collisions int64  // number of entries chained to an occupied slot (atomic)
+	randIter   uint64 // cheap pseudo-random offset & probes
+
+	// tag index (see tags.go)
+	tags   map[string]map[*model.Entry]struct{}
+	tagged int64 // number of tagged entries (atomic)
+
+	// pinned entries (see pin.go)
+	pinned    map[*model.Entry]int64 // pinned entry -> weight charged to pins
+	pinnedMem int64                  // total weight charged by the shard
+	pins      *pinBudget             // pinned budget (shared by the shards of a map)
 
 	// LRU (enabled in Listing mode)
 	lruOn bool
 	lru   *list.List
 	lidx  map[uint64]*list.Element
 
+	// W-TinyLFU segments (enabled in Windowed mode, see window.go)
+	win *window
+
 	rq queue.Queue
 }
 
 // NewShard creates a shard with small map capacity and fixed-size reservoirs.
 func NewShard(id uint64) *Shard {
-	sh := &Shard{id: id, items: make(map[uint64]*model.Entry)}
+	sh := &Shard{id: id, items: make(map[uint64]*model.Entry), pins: unlimitedPins()}
 	sh.rq.Init(queueCap)
 	return sh
 }
@@ -45,31 +58,53 @@ func (sh *Shard) ID() uint64         { return sh.id }
 func (sh *Shard) Weight() int64      { return atomic.LoadInt64(&sh.mem) }
 func (sh *Shard) Len() int64         { return atomic.LoadInt64(&sh.len) }
 func (sh *Shard) AddMem(delta int64) { atomic.AddInt64(&sh.mem, delta) }
+func (sh *Shard) Collisions() int64  { return atomic.LoadInt64(&sh.collisions) }
 
 // Set inserts or updates a key. Returns deltas for global aggregations.
 func (sh *Shard) Set(key uint64, new *model.Entry) (bytesDelta int64, lenDelta int64) {
 	sh.Lock()
-	if old, hit := sh.items[key]; hit {
-		sh.items[key] = new
+	bytesDelta, lenDelta = sh.SetUnlocked(key, new)
+	sh.Unlock()
+	return
+}
+
+// SetUnlocked inserts or updates a key when the shard is already exclusively locked.
+// An entry with the same full key is replaced, otherwise the new one is chained to the slot.
+func (sh *Shard) SetUnlocked(key uint64, new *model.Entry) (bytesDelta int64, lenDelta int64) {
+	if old, hit := sh.findUnlocked(key, new.Key()); hit {
+		sh.replaceUnlocked(key, old, new)
 		sh.lruOnAccessUnlocked(key)
 
 		lenDelta = 0
 		bytesDelta = new.Weight() - old.Weight()
 		atomic.AddInt64(&sh.mem, bytesDelta)
 	} else {
-		sh.items[key] = new
-		sh.lruOnInsertUnlocked(key)
+		sh.linkUnlocked(key, new)
 
 		lenDelta = 1
 		bytesDelta = new.Weight()
 		atomic.AddInt64(&sh.len, lenDelta)
 		atomic.AddInt64(&sh.mem, bytesDelta)
 	}
+	return
+}
+
+// SetIfAbsent inserts a key only if there is no entry with the same full key. Returns deltas for global aggregations.
+func (sh *Shard) SetIfAbsent(key uint64, new *model.Entry) (bytesDelta int64, inserted bool) {
+	sh.Lock()
+	if _, hit := sh.findUnlocked(key, new.Key()); !hit {
+		sh.linkUnlocked(key, new)
+
+		inserted = true
+		bytesDelta = new.Weight()
+		atomic.AddInt64(&sh.len, 1)
+		atomic.AddInt64(&sh.mem, bytesDelta)
+	}
 	sh.Unlock()
 	return
 }
 
-// Get reads a value under a shared lock.
+// Get reads a value of the slot (the head of its collision chain) under a shared lock.
 func (sh *Shard) Get(key uint64) (value *model.Entry, hit bool) {
 	sh.RLock()
 	value, hit = sh.items[key]
@@ -77,7 +112,15 @@ func (sh *Shard) Get(key uint64) (value *model.Entry, hit bool) {
 	return
 }
 
-// Remove deletes a key under the write lock.
+// GetKey reads the value of the full key under a shared lock.
+func (sh *Shard) GetKey(k *pubmodel.Key) (value *model.Entry, hit bool) {
+	sh.RLock()
+	value, hit = sh.findUnlocked(k.Value(), k)
+	sh.RUnlock()
+	return
+}
+
+// Remove deletes a slot under the write lock.
 func (sh *Shard) Remove(key uint64) (freedBytes int64, hit bool) {
 	sh.Lock()
 	freedBytes, hit = sh.RemoveUnlocked(key)
@@ -85,20 +128,30 @@ func (sh *Shard) Remove(key uint64) (freedBytes int64, hit bool) {
 	return
 }
 
-// RemoveUnlocked deletes a key when the shard is already exclusively locked.
-func (sh *Shard) RemoveUnlocked(key uint64) (freedBytes int64, hit bool) {
-	var old *model.Entry
-	if old, hit = sh.items[key]; hit {
-		delete(sh.items, key)
-		sh.lruOnDeleteUnlocked(key)
-
-		freedBytes = old.Weight()
-		atomic.AddInt64(&sh.mem, -freedBytes)
-		atomic.AddInt64(&sh.len, -1)
+// RemoveKey deletes the value of the full key under the write lock.
+func (sh *Shard) RemoveKey(k *pubmodel.Key) (freedBytes int64, hit bool) {
+	sh.Lock()
+	if entry, found := sh.findUnlocked(k.Value(), k); found {
+		freedBytes, hit = sh.removeEntryUnlocked(k.Value(), entry)
 	}
+	sh.Unlock()
+	return
+}
+
+// RemoveValue deletes a key only if it still holds the given value.
+func (sh *Shard) RemoveValue(key uint64, value *model.Entry) (freedBytes int64, hit bool) {
+	sh.Lock()
+	freedBytes, hit = sh.removeEntryUnlocked(key, value)
+	sh.Unlock()
 	return
 }
 
+// RemoveUnlocked deletes a slot with all chained entries when the shard is already exclusively locked.
+func (sh *Shard) RemoveUnlocked(key uint64) (freedBytes int64, hit bool) {
+	freedBytes, removed := sh.removeSlotUnlocked(key)
+	return freedBytes, removed > 0
+}
+
 // Clear removes all entries and returns (freedBytes, itemsRemoved).
 // Reservoirs are kept intact; stale keys are naturally validated&skipped.
 func (sh *Shard) Clear() (freedBytes int64, items int64) {
@@ -107,6 +160,11 @@ func (sh *Shard) Clear() (freedBytes int64, items int64) {
 	freedBytes = atomic.LoadInt64(&sh.mem)
 
 	sh.items = make(map[uint64]*model.Entry, items)
+	sh.tags = nil
+	atomic.StoreInt64(&sh.tagged, 0)
+	sh.pinned = nil
+	sh.pins.release(sh.pinnedMem)
+	sh.pinnedMem = 0
 
 	atomic.StoreInt64(&sh.len, 0)
 	atomic.StoreInt64(&sh.mem, 0)
@@ -116,6 +174,9 @@ func (sh *Shard) Clear() (freedBytes int64, items int64) {
 	if sh.lidx != nil {
 		clear(sh.lidx)
 	}
+	if sh.win != nil {
+		sh.win.reset()
+	}
 	sh.Unlock()
 	return
 }
@@ -124,13 +185,15 @@ func (sh *Shard) Clear() (freedBytes int64, items int64) {
 func (sh *Shard) WalkR(ctx context.Context, fn func(uint64, *model.Entry) bool) {
 	sh.RLock()
 	defer sh.RUnlock()
-	for k, v := range sh.items {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			if !fn(k, v) {
+	for k, head := range sh.items {
+		for v := head; v != nil; v = v.Next() {
+			select {
+			case <-ctx.Done():
 				return
+			default:
+				if !fn(k, v) {
+					return
+				}
 			}
 		}
 	}
@@ -145,13 +208,15 @@ func (sh *Shard) Walk(ctx context.Context, fn func(cache pubmodel.CacheItem) boo
 		sh.RLock()
 		defer sh.RUnlock()
 	}
-	for _, v := range sh.items {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			if !fn(v) {
+	for _, head := range sh.items {
+		for v := head; v != nil; v = v.Next() {
+			select {
+			case <-ctx.Done():
 				return
+			default:
+				if !fn(v) {
+					return
+				}
 			}
 		}
 	}
@@ -161,13 +226,15 @@ func (sh *Shard) Walk(ctx context.Context, fn func(cache pubmodel.CacheItem) boo
 func (sh *Shard) WalkW(ctx context.Context, fn func(uint64, *model.Entry) bool) {
 	sh.Lock()
 	defer sh.Unlock()
-	for k, v := range sh.items {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			if !fn(k, v) {
+	for k, head := range sh.items {
+		for v := head; v != nil; v = v.Next() {
+			select {
+			case <-ctx.Done():
 				return
+			default:
+				if !fn(k, v) {
+					return
+				}
 			}
 		}
 	}
@@ -198,3 +265,16 @@ func (sh *Shard) tryLock() bool {
 	}
 	return false
 }
+
+// AppendEntries appends all entries of the shard to buf under a shared lock, so the lock is released
+// before the caller handles them.
+func (sh *Shard) AppendEntries(buf []*model.Entry) []*model.Entry {
+	sh.RLock()
+	defer sh.RUnlock()
+	for _, head := range sh.items {
+		for e := head; e != nil; e = e.Next() {
+			buf = append(buf, e)
+		}
+	}
+	return buf
+}
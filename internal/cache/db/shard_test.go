@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
 	"github.com/stretchr/testify/require"
 	"sync"
 	"testing"
@@ -27,9 +28,9 @@ func TestShard_Set_Insert(t *testing.T) {
 func TestShard_Set_Update(t *testing.T) {
 	sh := NewShard(0)
 	key := uint64(123)
-	entry1 := model.NewEntry(model.NewKey("test1"), 0, false)
+	entry1 := model.NewEntry(model.NewKey("test"), 0, false)
 	entry1.SetPayload([]byte("small"))
-	entry2 := model.NewEntry(model.NewKey("test2"), 0, false)
+	entry2 := model.NewEntry(model.NewKey("test"), 0, false)
 	entry2.SetPayload(make([]byte, 1024)) // larger payload
 
 	sh.Set(key, entry1)
@@ -279,3 +280,77 @@ func TestShard_ConcurrentRemove(t *testing.T) {
 	require.Equal(t, int64(0), sh.Len())
 	require.Equal(t, int64(0), sh.Weight())
 }
+
+// TestShard_SetIfAbsent inserts only when the key is free.
+func TestShard_SetIfAbsent(t *testing.T) {
+	sh := NewShard(0)
+	key := uint64(123)
+	entry1 := model.NewEntry(model.NewKey("test"), 0, false)
+	entry1.SetPayload([]byte("data1"))
+	entry2 := model.NewEntry(model.NewKey("test"), 0, false)
+	entry2.SetPayload([]byte("data2"))
+
+	bytesDelta, inserted := sh.SetIfAbsent(key, entry1)
+	require.True(t, inserted)
+	require.Equal(t, entry1.Weight(), bytesDelta)
+
+	bytesDelta, inserted = sh.SetIfAbsent(key, entry2)
+	require.False(t, inserted)
+	require.Equal(t, int64(0), bytesDelta)
+
+	retrieved, found := sh.Get(key)
+	require.True(t, found)
+	require.Equal(t, entry1, retrieved)
+	require.Equal(t, int64(1), sh.Len())
+}
+
+// TestShard_Collisions_AreChained keeps keys with the same 64-bit hash side by side.
+func TestShard_Collisions_AreChained(t *testing.T) {
+	sh := NewShard(0)
+	key := uint64(123)
+	// same slot, different full keys
+	entry1 := model.NewEntry(pubmodel.NewKey(key, 1, 1), 0, false)
+	entry1.SetPayload([]byte("data1"))
+	entry2 := model.NewEntry(pubmodel.NewKey(key, 2, 2), 0, false)
+	entry2.SetPayload([]byte("data22"))
+	entry3 := model.NewEntry(pubmodel.NewKey(key, 3, 3), 0, false)
+	entry3.SetPayload([]byte("data333"))
+
+	for _, entry := range []*model.Entry{entry1, entry2, entry3} {
+		_, lenDelta := sh.Set(key, entry)
+		require.Equal(t, int64(1), lenDelta)
+	}
+	require.Equal(t, int64(3), sh.Len())
+	require.Equal(t, int64(2), sh.Collisions())
+	require.Equal(t, entry1.Weight()+entry2.Weight()+entry3.Weight(), sh.Weight())
+
+	for _, entry := range []*model.Entry{entry1, entry2, entry3} {
+		found, ok := sh.GetKey(entry.Key())
+		require.True(t, ok)
+		require.Same(t, entry, found)
+	}
+
+	// replacing an entry in the middle of the chain keeps the others
+	update := model.NewEntry(pubmodel.NewKey(key, 2, 2), 0, false)
+	update.SetPayload([]byte("updated"))
+	_, lenDelta := sh.Set(key, update)
+	require.Equal(t, int64(0), lenDelta)
+	found, _ := sh.GetKey(entry2.Key())
+	require.Same(t, update, found)
+
+	freed, hit := sh.RemoveKey(update.Key())
+	require.True(t, hit)
+	require.Equal(t, update.Weight(), freed)
+	_, ok := sh.GetKey(entry1.Key())
+	require.True(t, ok)
+	_, ok = sh.GetKey(entry3.Key())
+	require.True(t, ok)
+	require.Equal(t, int64(2), sh.Len())
+	require.Equal(t, entry1.Weight()+entry3.Weight(), sh.Weight())
+
+	// removing the slot drops the whole chain
+	_, hit = sh.Remove(key)
+	require.True(t, hit)
+	require.Equal(t, int64(0), sh.Len())
+	require.Equal(t, int64(0), sh.Weight())
+}
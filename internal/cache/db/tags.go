@@ -0,0 +1,85 @@
+package db
+
+import (
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	"sync/atomic"
+)
+
+// Each shard indexes tags of its own entries (tag -> set of tagged entries), so the index is guarded
+// by the shard lock and kept in sync by the chain primitives: entries are indexed when linked
+// and dropped from the index when unlinked, removed with their slot or evicted from the LRU tail.
+// Memory of the index is a part of the entry weight (see model.Entry.Weight).
+
+// InvalidateTag removes all entries tagged by the tag and adjusts global counters.
+func (m *Map) InvalidateTag(tag string) (freedBytes, removed int64) {
+	return m.InvalidateTags(tag)
+}
+
+// InvalidateTags removes all entries tagged by any of the tags; each shard is locked once.
+func (m *Map) InvalidateTags(tags ...string) (freedBytes, removed int64) {
+	for _, sh := range m.shards {
+		bytes, n := sh.InvalidateTags(tags...)
+		if n > 0 {
+			atomic.AddInt64(&m.mem, -bytes)
+			atomic.AddInt64(&m.len, -n)
+			freedBytes += bytes
+			removed += n
+		}
+	}
+	return
+}
+
+// InvalidateTags removes entries of the shard tagged by any of the tags.
+func (sh *Shard) InvalidateTags(tags ...string) (freedBytes, removed int64) {
+	if atomic.LoadInt64(&sh.tagged) == 0 {
+		return 0, 0
+	}
+	sh.Lock()
+	defer sh.Unlock()
+	for _, tag := range tags {
+		for entry := range sh.tags[tag] {
+			if bytes, hit := sh.removeEntryUnlocked(entry.Key().Value(), entry); hit {
+				freedBytes += bytes
+				removed++
+			}
+		}
+	}
+	return
+}
+
+// tagUnlocked indexes tags of a linked entry.
+func (sh *Shard) tagUnlocked(entry *model.Entry) {
+	tags := entry.Tags()
+	if len(tags) == 0 {
+		return
+	}
+	if sh.tags == nil {
+		sh.tags = make(map[string]map[*model.Entry]struct{})
+	}
+	for _, tag := range tags {
+		set, ok := sh.tags[tag]
+		if !ok {
+			set = make(map[*model.Entry]struct{})
+			sh.tags[tag] = set
+		}
+		set[entry] = struct{}{}
+	}
+	atomic.AddInt64(&sh.tagged, 1)
+}
+
+// untagUnlocked drops an unlinked entry from the index.
+func (sh *Shard) untagUnlocked(entry *model.Entry) {
+	tags := entry.Tags()
+	if len(tags) == 0 {
+		return
+	}
+	for _, tag := range tags {
+		if set, ok := sh.tags[tag]; ok {
+			delete(set, entry)
+			if len(set) == 0 {
+				delete(sh.tags, tag)
+			}
+		}
+	}
+	atomic.AddInt64(&sh.tagged, -1)
+}
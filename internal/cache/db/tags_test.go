@@ -0,0 +1,59 @@
+package db
+
+import (
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newTaggedEntry(k *pubmodel.Key, tags ...string) *model.Entry {
+	entry := model.NewEntry(k, 0, false)
+	entry.AddTags(tags...)
+	entry.SetPayload([]byte("data"))
+	return entry
+}
+
+// TestShard_InvalidateTags removes tagged entries only and keeps counters in sync.
+func TestShard_InvalidateTags(t *testing.T) {
+	sh := NewShard(0)
+	product := newTaggedEntry(pubmodel.NewKey(1, 1, 1), "product:1", "category:1")
+	category := newTaggedEntry(pubmodel.NewKey(2, 2, 2), "category:1")
+	plain := newTaggedEntry(pubmodel.NewKey(3, 3, 3))
+	for _, entry := range []*model.Entry{product, category, plain} {
+		sh.Set(entry.Key().Value(), entry)
+	}
+
+	freed, removed := sh.InvalidateTags("product:1")
+	require.Equal(t, int64(1), removed)
+	require.Equal(t, product.Weight(), freed)
+	require.Equal(t, category.Weight()+plain.Weight(), sh.Weight())
+
+	freed, removed = sh.InvalidateTags("category:1", "absent")
+	require.Equal(t, int64(1), removed)
+	require.Equal(t, category.Weight(), freed)
+	require.Equal(t, int64(1), sh.Len())
+	require.Empty(t, sh.tags)
+}
+
+// TestShard_Tags_IndexCleanedUp drops entries from the tag index on replacement, removal and LRU eviction.
+func TestShard_Tags_IndexCleanedUp(t *testing.T) {
+	sh := NewShard(0)
+	sh.enableLRU()
+
+	key := pubmodel.NewKey(1, 1, 1)
+	sh.Set(key.Value(), newTaggedEntry(key, "a"))
+	// replacement reindexes the entry
+	sh.Set(key.Value(), newTaggedEntry(key, "b"))
+	require.NotContains(t, sh.tags, "a")
+	require.Len(t, sh.tags["b"], 1)
+
+	sh.Remove(key.Value())
+	require.Empty(t, sh.tags)
+
+	sh.Set(key.Value(), newTaggedEntry(key, "c"))
+	_, _, ok := sh.lruPopTail()
+	require.True(t, ok)
+	require.Empty(t, sh.tags)
+	require.Zero(t, sh.tagged)
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"container/list"
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	"math"
+	"sync/atomic"
+)
+
+// W-TinyLFU (Windowed mode) keeps the slots of a shard in three LRU segments:
+//   - window: every new slot lands here, so newcomers gather frequency before they compete
+//     for a place in the main space; it holds the shard's part of the WindowFraction share of the entries
+//     of the map (see windowLimit);
+//   - probation: the entry point of the main space; the window overflow moves here as candidates;
+//   - protected: slots hit while in probation, up to protectedShare of the main space;
+//     its overflow is demoted back to probation.
+//
+// An eviction lets the newest candidate compete with the probation tail: TinyLFU keeps the more
+// frequent one, the other one goes. A candidate which won stays in probation as a regular slot.
+
+// Admitter decides whether a candidate should take the place of a victim (see bloom.AdmissionControl).
+type Admitter interface {
+	Allow(candidate, victim uint64) bool
+}
+
+type admitAll struct{}
+
+func (admitAll) Allow(_, _ uint64) bool { return true }
+
+type segment uint8
+
+const (
+	windowSeg segment = iota
+	probationSeg
+	protectedSeg
+	numOfSegments
+)
+
+// evictionOrder is the order segments give up their tails in.
+var evictionOrder = [...]segment{probationSeg, protectedSeg, windowSeg}
+
+// protectedShare is the part of the main space (probation + protected) the protected segment may take.
+const protectedShare = 0.8
+
+type windowSlot struct {
+	key       uint64
+	seg       segment
+	candidate bool // moved from the window and not contested yet
+}
+
+type window struct {
+	segs     [numOfSegments]list.List
+	idx      map[uint64]*list.Element // slot -> element of its segment
+	fraction *atomic.Uint64           // share of entries kept in the window segment (float64 bits, owned by Map)
+	entries  *int64                   // number of entries of the map (atomic, owned by Map); nil for a standalone shard
+	id       int                      // index of the shard in the map
+}
+
+func newWindow(fraction *atomic.Uint64, entries *int64, id, capacity int) *window {
+	return &window{idx: make(map[uint64]*list.Element, capacity), fraction: fraction, entries: entries, id: id}
+}
+
+// insert puts a new slot to the front of the window and moves the window overflow to probation.
+func (w *window) insert(key uint64) {
+	if w.idx[key] != nil {
+		w.access(key)
+		return
+	}
+	w.idx[key] = w.segs[windowSeg].PushFront(&windowSlot{key: key, seg: windowSeg})
+	for limit := w.windowLimit(); w.segs[windowSeg].Len() > limit; {
+		w.moveToFront(w.segs[windowSeg].Back(), probationSeg).candidate = true
+	}
+}
+
+// access moves a hit slot to the front of its segment; a probation hit is promoted to protected.
+func (w *window) access(key uint64) {
+	el := w.idx[key]
+	if el == nil {
+		return
+	}
+	s := el.Value.(*windowSlot)
+	if s.seg != probationSeg {
+		w.segs[s.seg].MoveToFront(el)
+		return
+	}
+	s.candidate = false
+	w.moveToFront(el, protectedSeg)
+	for limit := w.protectedLimit(); w.segs[protectedSeg].Len() > limit; {
+		w.moveToFront(w.segs[protectedSeg].Back(), probationSeg)
+	}
+}
+
+func (w *window) remove(key uint64) {
+	if el := w.idx[key]; el != nil {
+		w.segs[el.Value.(*windowSlot).seg].Remove(el)
+		delete(w.idx, key)
+	}
+}
+
+func (w *window) reset() {
+	for i := range w.segs {
+		w.segs[i].Init()
+	}
+	clear(w.idx)
+}
+
+func (w *window) moveToFront(el *list.Element, to segment) *windowSlot {
+	s := el.Value.(*windowSlot)
+	w.segs[s.seg].Remove(el)
+	s.seg = to
+	w.idx[s.key] = w.segs[to].PushFront(s)
+	return s
+}
+
+// windowLimit is the part of the window budget (the fraction of the entries of the map) the shard takes.
+// The budget is split between the shards evenly and the first ones take a slot of the remainder each,
+// so the windows of a small cache sum up to the budget (a shard may have no window at all) rather than
+// to a slot per shard; a standalone shard takes the fraction of its own slots.
+func (w *window) windowLimit() int {
+	entries, shards := int64(len(w.idx)), 1
+	if w.entries != nil {
+		entries, shards = atomic.LoadInt64(w.entries), NumOfShards
+	}
+	budget := int(float64(entries) * math.Float64frombits(w.fraction.Load()))
+	limit := budget / shards
+	if w.id%shards < budget%shards {
+		limit++
+	}
+	return limit
+}
+
+func (w *window) protectedLimit() int {
+	return max(1, int(float64(w.segs[probationSeg].Len()+w.segs[protectedSeg].Len())*protectedShare))
+}
+
+// enableWindow turns on Windowed mode; entries is the number of entries of the map the window budget is split from
+// (nil for a standalone shard).
+func (sh *Shard) enableWindow(fraction *atomic.Uint64, entries *int64) {
+	sh.Lock()
+	if sh.win == nil {
+		sh.win = newWindow(fraction, entries, int(sh.id), len(sh.items))
+		for k := range sh.items {
+			sh.win.insert(k)
+		}
+	}
+	sh.Unlock()
+}
+
+// windowPop evicts the slot chosen by W-TinyLFU: the newest candidate competes with the probation tail
+// (with the protected tail if probation holds nothing else) and the one admitter rejects goes.
+// Pinned slots are skipped like in lruPopTail; priorities are not taken into account (TinyLFU decides).
+func (sh *Shard) windowPop(admitter Admitter) (key uint64, val *model.Entry, ok bool) {
+	if sh.win == nil {
+		return 0, nil, false
+	}
+	sh.Lock()
+	defer sh.Unlock()
+
+	victim := sh.windowVictimUnlocked()
+	if victim == nil {
+		return 0, nil, false
+	}
+	evict := victim
+	if el := sh.win.segs[probationSeg].Front(); el != nil && el != victim {
+		if s := el.Value.(*windowSlot); s.candidate && !isChainPinned(sh.items[s.key]) {
+			s.candidate = false
+			if !admitter.Allow(s.key, victim.Value.(*windowSlot).key) {
+				evict = el
+			}
+		}
+	}
+
+	k := evict.Value.(*windowSlot).key
+	sh.win.remove(k)
+	v, ok := sh.items[k]
+	if !ok {
+		return 0, nil, false
+	}
+	// the whole collision chain goes with its slot
+	delete(sh.items, k)
+	sh.unindexChainUnlocked(v)
+	freed, n := chainWeight(v)
+	atomic.AddInt64(&sh.len, -n)
+	atomic.AddInt64(&sh.mem, -freed)
+	return k, v, true
+}
+
+// windowVictimUnlocked returns the first unpinned tail of probation, protected and window segments (in this order).
+// Pinned slots met on the way are moved to the front of their segment, each one once at most.
+func (sh *Shard) windowVictimUnlocked() *list.Element {
+	for _, seg := range evictionOrder {
+		l := &sh.win.segs[seg]
+		el := l.Back()
+		for visited := 0; el != nil && visited < l.Len(); visited++ {
+			if !isChainPinned(sh.items[el.Value.(*windowSlot).key]) {
+				return el
+			}
+			prev := el.Prev()
+			l.MoveToFront(el)
+			el = prev
+		}
+	}
+	return nil
+}
+
+// windowPeekTailK is lruPeekTailK in Windowed mode: it looks through up to k tail slots of probation,
+// protected and window segments (in this order).
+func (sh *Shard) windowPeekTailK(k int, chooseFn func(*model.Entry) bool) (v *model.Entry, ok bool) {
+	sh.RLock()
+	defer sh.RUnlock()
+
+	for _, seg := range evictionOrder {
+		e := sh.win.segs[seg].Back()
+		for ; k > 0 && e != nil; k, e = k-1, e.Prev() {
+			for vv := sh.items[e.Value.(*windowSlot).key]; vv != nil; vv = vv.Next() {
+				if chooseFn(vv) {
+					return vv, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"github.com/Borislavv/go-ash-cache/config"
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+type admitFunc func(candidate, victim uint64) bool
+
+func (f admitFunc) Allow(candidate, victim uint64) bool { return f(candidate, victim) }
+
+func newWindowedShard(fraction float64, keys ...uint64) *Shard {
+	var bits atomic.Uint64
+	bits.Store(math.Float64bits(fraction))
+
+	sh := NewShard(0)
+	sh.enableWindow(&bits, nil)
+	for _, k := range keys {
+		entry := model.NewEntry(model.NewKey(strconv.FormatUint(k, 10)), 0, false)
+		entry.SetPayload([]byte("data"))
+		sh.Set(k, entry)
+	}
+	return sh
+}
+
+func segmentKeys(sh *Shard, seg segment) (keys []uint64) {
+	for el := sh.win.segs[seg].Front(); el != nil; el = el.Next() {
+		keys = append(keys, el.Value.(*windowSlot).key)
+	}
+	return keys
+}
+
+// TestShard_Window_Segments checks that the window keeps its share of new slots, the overflow goes
+// to probation and a probation hit is promoted to protected.
+func TestShard_Window_Segments(t *testing.T) {
+	sh := newWindowedShard(0.25, 1, 2, 3, 4, 5, 6, 7, 8)
+
+	require.Equal(t, []uint64{8, 7}, segmentKeys(sh, windowSeg))
+	require.Equal(t, []uint64{6, 5, 4, 3, 2, 1}, segmentKeys(sh, probationSeg))
+
+	sh.touchLRU(1)
+	sh.touchLRU(7)
+	require.Equal(t, []uint64{7, 8}, segmentKeys(sh, windowSeg))
+	require.Equal(t, []uint64{6, 5, 4, 3, 2}, segmentKeys(sh, probationSeg))
+	require.Equal(t, []uint64{1}, segmentKeys(sh, protectedSeg))
+
+	sh.Remove(1)
+	require.Empty(t, segmentKeys(sh, protectedSeg))
+	require.Len(t, sh.win.idx, 7)
+}
+
+// TestShard_WindowPop_AdmitterDecides checks that the newest candidate from the window competes with the probation tail.
+func TestShard_WindowPop_AdmitterDecides(t *testing.T) {
+	reject := admitFunc(func(_, _ uint64) bool { return false })
+	admit := admitFunc(func(_, _ uint64) bool { return true })
+
+	// window: 4, 3; probation: 2 (candidate), 1
+	sh := newWindowedShard(0.5, 1, 2, 3, 4)
+	key, _, ok := sh.windowPop(reject)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), key, "rejected candidate goes")
+
+	sh = newWindowedShard(0.5, 1, 2, 3, 4)
+	key, _, ok = sh.windowPop(admit)
+	require.True(t, ok)
+	require.Equal(t, uint64(1), key, "admitted candidate takes the place of the victim")
+	require.Equal(t, []uint64{2}, segmentKeys(sh, probationSeg))
+	require.EqualValues(t, 3, sh.Len())
+
+	// the only probation slot is the victim itself, no contest
+	key, _, ok = sh.windowPop(reject)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), key)
+}
+
+// TestShard_WindowPop_SkipsPinnedTails evicts past any number of pinned tail slots.
+func TestShard_WindowPop_SkipsPinnedTails(t *testing.T) {
+	sh := newWindowedShard(0.5)
+	const pinned = 2 * pinnedSkips
+	for k := uint64(0); k <= pinned; k++ {
+		entry := model.NewEntry(model.NewKey(strconv.FormatUint(k, 10)), 0, false)
+		if k < pinned {
+			entry.Pin()
+		}
+		entry.SetPayload([]byte("data"))
+		sh.Set(k, entry)
+	}
+
+	key, _, ok := sh.windowPop(admitAll{})
+	require.True(t, ok)
+	require.Equal(t, uint64(pinned), key)
+
+	_, _, ok = sh.windowPop(admitAll{})
+	require.False(t, ok, "pinned slots only")
+	require.Len(t, sh.win.idx, pinned)
+}
+
+// TestMap_Window_SplitsBudget sizes the windows of all shards by the fraction of the entries of the map,
+// so a small cache doesn't get a window slot per shard.
+func TestMap_Window_SplitsBudget(t *testing.T) {
+	cfg := &config.Cache{
+		DB:       config.DBCfg{SizeBytes: 10 * 1024 * 1024},
+		Eviction: &config.EvictionCfg{LRUMode: config.LRUModeWindowed, WindowFraction: 0.1},
+	}
+	cfg.AdjustConfig()
+	m := NewMap(context.Background(), cfg)
+
+	windowLen := func() (n int) {
+		for _, sh := range m.shards {
+			n += sh.win.segs[windowSeg].Len()
+		}
+		return n
+	}
+
+	for i := 0; i < 100; i++ {
+		entry := model.NewEntry(model.NewKey(strconv.Itoa(i)), 0, false)
+		entry.SetPayload([]byte("data"))
+		m.Set(entry.Key().Value(), entry)
+	}
+	require.LessOrEqual(t, windowLen(), 10)
+
+	for i := 100; i < 100*NumOfShards; i++ {
+		entry := model.NewEntry(model.NewKey(strconv.Itoa(i)), 0, false)
+		entry.SetPayload([]byte("data"))
+		m.Set(entry.Key().Value(), entry)
+	}
+	require.InDelta(t, 10*NumOfShards, windowLen(), NumOfShards, "about the fraction of entries in total")
+}
@@ -0,0 +1,82 @@
+// Package flight deduplicates concurrent loads of the same key: while one caller
+// runs a loader, the others wait for its payload or error instead of running their own.
+package flight
+
+import (
+	"context"
+	"errors"
+	"github.com/Borislavv/go-ash-cache/internal/cache/db"
+	"github.com/Borislavv/go-ash-cache/model"
+	"sync"
+)
+
+// ErrLoaderPanicked is returned to the waiters of a call whose loader panicked.
+var ErrLoaderPanicked = errors.New("loader panicked")
+
+// sharded the same way as db.Map to keep contention on the in-flight index low.
+const shardMask = db.NumOfShards - 1
+
+// call is an in-flight (or completed) loader execution.
+type call struct {
+	done    chan struct{}
+	key     *model.Key
+	payload []byte
+	err     error
+}
+
+type shard struct {
+	sync.Mutex
+	calls map[uint64]*call
+}
+
+// Group tracks in-flight loads per key.
+type Group struct {
+	shards [db.NumOfShards]shard
+}
+
+func New() *Group {
+	g := &Group{}
+	for i := range g.shards {
+		g.shards[i].calls = make(map[uint64]*call)
+	}
+	return g
+}
+
+// Do executes fn and returns its results, making sure that only one execution is in-flight
+// for a given key at a time. If a duplicate comes in, it waits for the original to complete
+// and receives the same results; shared reports whether the results were received that way.
+// A duplicate stops waiting when its ctx is done. Keys are matched by the full 128-bit hash,
+// so colliding 64-bit keys never share a result.
+func (g *Group) Do(ctx context.Context, key *model.Key, fn func() ([]byte, error)) (payload []byte, shared bool, err error) {
+	sh := &g.shards[key.Value()&shardMask]
+
+	sh.Lock()
+	if c, found := sh.calls[key.Value()]; found {
+		if !c.key.IsTheSame(key) {
+			// hash collision: the slot is busy with another key, run without deduplication
+			sh.Unlock()
+			payload, err = fn()
+			return payload, false, err
+		}
+		sh.Unlock()
+		select {
+		case <-c.done:
+			return c.payload, true, c.err
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+	c := &call{key: key, done: make(chan struct{}), err: ErrLoaderPanicked} // err is overwritten unless fn panics
+	sh.calls[key.Value()] = c
+	sh.Unlock()
+
+	defer func() {
+		sh.Lock()
+		delete(sh.calls, key.Value())
+		sh.Unlock()
+		close(c.done)
+	}()
+
+	c.payload, c.err = fn()
+	return c.payload, false, c.err
+}
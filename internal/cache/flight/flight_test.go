@@ -0,0 +1,152 @@
+package flight
+
+import (
+	"context"
+	"errors"
+	"github.com/Borislavv/go-ash-cache/model"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestGroup_Do_DeduplicatesConcurrentCalls runs fn once for concurrent callers of the same key.
+func TestGroup_Do_DeduplicatesConcurrentCalls(t *testing.T) {
+	g := New()
+	key := model.NewKey(1, 2, 3)
+
+	const callers = 32
+	var (
+		wg      sync.WaitGroup
+		invokes atomic.Int64
+		release = make(chan struct{})
+		results = make(chan result, callers)
+	)
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			payload, shared, err := g.Do(context.Background(), key, func() ([]byte, error) {
+				invokes.Add(1)
+				<-release
+				return []byte("data"), nil
+			})
+			results <- result{payload: payload, shared: shared, err: err}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	var shares int
+	for res := range results {
+		require.NoError(t, res.err)
+		require.Equal(t, []byte("data"), res.payload)
+		if res.shared {
+			shares++
+		}
+	}
+	require.Equal(t, int64(1), invokes.Load())
+	require.Equal(t, callers-1, shares)
+}
+
+// result is the outcome of a Do call made by a spawned goroutine, checked by the test goroutine.
+type result struct {
+	payload []byte
+	shared  bool
+	err     error
+}
+
+// TestGroup_Do_SharesError propagates the loader error to all waiters.
+func TestGroup_Do_SharesError(t *testing.T) {
+	g := New()
+	key := model.NewKey(1, 2, 3)
+	testErr := errors.New("loader error")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_, _, _ = g.Do(context.Background(), key, func() ([]byte, error) {
+			close(started)
+			<-release
+			return nil, testErr
+		})
+	}()
+	<-started
+
+	done := make(chan result)
+	go func() {
+		payload, shared, err := g.Do(context.Background(), key, func() ([]byte, error) { return []byte("unexpected"), nil })
+		done <- result{payload: payload, shared: shared, err: err}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	res := <-done
+	require.True(t, res.shared)
+	require.ErrorIs(t, res.err, testErr)
+}
+
+// TestGroup_Do_CollisionIsNotShared never shares results between keys with the same 64-bit hash.
+func TestGroup_Do_CollisionIsNotShared(t *testing.T) {
+	g := New()
+	key1 := model.NewKey(1, 2, 3)
+	key2 := model.NewKey(1, 4, 5)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_, _, _ = g.Do(context.Background(), key1, func() ([]byte, error) {
+			close(started)
+			<-release
+			return []byte("key1"), nil
+		})
+	}()
+	<-started
+	defer close(release)
+
+	payload, shared, err := g.Do(context.Background(), key2, func() ([]byte, error) { return []byte("key2"), nil })
+	require.NoError(t, err)
+	require.False(t, shared)
+	require.Equal(t, []byte("key2"), payload)
+}
+
+// TestGroup_Do_ReleasesKey allows a new call once the previous one completed.
+func TestGroup_Do_ReleasesKey(t *testing.T) {
+	g := New()
+	key := model.NewKey(1, 2, 3)
+
+	for i := 0; i < 3; i++ {
+		_, shared, err := g.Do(context.Background(), key, func() ([]byte, error) { return nil, nil })
+		require.NoError(t, err)
+		require.False(t, shared)
+	}
+}
+
+// TestGroup_Do_WaiterRespectsContext stops waiting for a slow call when the waiter's ctx is done.
+func TestGroup_Do_WaiterRespectsContext(t *testing.T) {
+	g := New()
+	key := model.NewKey(1, 2, 3)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_, _, _ = g.Do(context.Background(), key, func() ([]byte, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	}()
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, shared, err := g.Do(ctx, key, func() ([]byte, error) { return nil, nil })
+	require.False(t, shared)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
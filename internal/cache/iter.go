@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"github.com/Borislavv/go-ash-cache/internal/cache/db"
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
+	"iter"
+)
+
+// All iterates over cached items (cached errors are skipped). Entries of one shard are copied under
+// its read lock which is released before yielding, so the loop body may be slow or break early
+// without blocking writers. Items stored or removed during an iteration may be missed.
+func (c *Cache) All() iter.Seq2[pubmodel.Key, pubmodel.ItemView] {
+	return c.AllFunc(nil)
+}
+
+// AllFunc is All yielding only items accepted by the filter (nil accepts all).
+// The filter runs outside the shard lock as well.
+func (c *Cache) AllFunc(
+	filter func(key pubmodel.Key, item pubmodel.ItemView) bool,
+) iter.Seq2[pubmodel.Key, pubmodel.ItemView] {
+	return func(yield func(pubmodel.Key, pubmodel.ItemView) bool) {
+		var batch []*model.Entry
+		defer func() { clear(batch) }() // don't pin removed entries
+
+		for idx := uint64(0); idx < db.NumOfShards; idx++ {
+			clear(batch)
+			batch = c.db.Shard(idx).AppendEntries(batch[:0])
+			for _, entry := range batch {
+				if entry.IsNegative() {
+					continue
+				}
+				key, view := *entry.Key(), entry.View()
+				if filter != nil && !filter(key, view) {
+					continue
+				}
+				if !yield(key, view) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package cache
+
+import (
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+)
+
+// Pin exempts the stored key from eviction. Returns false if the key is absent or pinning it
+// would exceed db.pinned_fraction of the cache size.
+func (c *Cache) Pin(key string) (ok bool) {
+	k := model.KeyOfString(key)
+	if _, found := c.peekValue(&k); !found {
+		return false
+	}
+	return c.db.Pin(&k)
+}
+
+// Unpin makes the stored key evictable again and reports whether it was pinned.
+func (c *Cache) Unpin(key string) (ok bool) {
+	k := model.KeyOfString(key)
+	return c.db.Unpin(&k)
+}
+
+// PinnedMem returns the weight charged by pinned entries (a part of Mem).
+func (c *Cache) PinnedMem() int64 { return c.db.PinnedMem() }
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"errors"
+	"github.com/Borislavv/go-ash-cache/internal/cache/db"
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+)
+
+// defaultScanCount is the page size hint of Scan when count is not positive.
+const defaultScanCount = 10
+
+// ErrKeysNotRetained is returned by DelPrefix and Scan if original keys are not retained (see db.retain_keys).
+var ErrKeysNotRetained = errors.New("original keys are not retained: enable db.retain_keys")
+
+// DelPrefix removes all keys starting with the prefix and returns their number (cached errors included).
+// Shards are locked one at a time, so the removal is not atomic across shards.
+func (c *Cache) DelPrefix(prefix string) (removed int64, err error) {
+	if !c.cfg.DB.RetainKeys {
+		return 0, ErrKeysNotRetained
+	}
+	_, removed = c.db.RemovePrefix(rawOf(prefix))
+	return removed, nil
+}
+
+// Scan iterates keys page by page like Redis SCAN: start with cursor 0 and pass the returned cursor
+// to the next call until it is 0. match is a glob pattern ('*' matches any sequence of bytes,
+// '?' a single byte; an empty pattern matches all keys). count is a hint of the page size:
+// a shard is always scanned as a whole, so a page may be larger. Keys stored or removed
+// during an iteration may be missed; cached errors are skipped.
+func (c *Cache) Scan(cursor uint64, match string, count int) (keys []string, next uint64, err error) {
+	if !c.cfg.DB.RetainKeys {
+		return nil, 0, ErrKeysNotRetained
+	}
+	if cursor >= db.NumOfShards {
+		return nil, 0, nil
+	}
+	if count <= 0 {
+		count = defaultScanCount
+	}
+	keys = make([]string, 0, count)
+	next = c.db.Scan(cursor, count, func(entry *model.Entry) bool {
+		if entry.IsNegative() || (match != "" && !matchGlob(match, entry.RawKey())) {
+			return false
+		}
+		keys = append(keys, string(entry.RawKey()))
+		return true
+	})
+	return keys, next, nil
+}
+
+// matchGlob reports whether the key matches the glob pattern of Scan.
+func matchGlob(pattern string, key []byte) bool {
+	p, k := 0, 0
+	starP, starK := -1, 0 // the last '*' of the pattern and the key position it was tried at
+	for k < len(key) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			starP, starK = p, k
+			p++
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == key[k]):
+			p, k = p+1, k+1
+		case starP >= 0:
+			// let the last '*' consume one more byte
+			starK++
+			p, k = starP+1, starK
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"github.com/Borislavv/go-ash-cache/internal/cache/db/model"
+	"github.com/Borislavv/go-ash-cache/internal/shared/cachedtime"
+	pubmodel "github.com/Borislavv/go-ash-cache/model"
+	"time"
+)
+
+// TTL returns how long the key has left before it expires, like Redis TTL: pubmodel.NoTTL if the key
+// never expires and pubmodel.NoKey if it is absent. An expired key kept for a refresh has zero left.
+func (c *Cache) TTL(key string) time.Duration {
+	k := model.KeyOfString(key)
+	entry, found := c.peekValue(&k)
+	if !found {
+		return pubmodel.NoKey
+	}
+	if remaining, ok := entry.RemainingTTL(); ok {
+		return remaining
+	}
+	return pubmodel.NoTTL
+}
+
+// Expire makes the key expire d from now, like Redis EXPIRE: a non-positive d removes the key.
+// Returns false if the key is absent.
+func (c *Cache) Expire(key string, d time.Duration) (ok bool) {
+	return c.ExpireAt(key, cachedtime.Now().Add(d))
+}
+
+// ExpireAt makes the key expire at t, like Redis EXPIREAT: a moment in the past removes the key.
+// Returns false if the key is absent.
+func (c *Cache) ExpireAt(key string, t time.Time) (ok bool) {
+	k := model.KeyOfString(key)
+	entry, found := c.peekValue(&k)
+	if !found {
+		return false
+	}
+	if t.UnixNano() <= cachedtime.UnixNano() {
+		_, ok = c.db.RemoveValue(k.Value(), entry)
+		return ok
+	}
+	entry.ExpireAt(t)
+	c.requeue(entry)
+	return true
+}
+
+// Persist removes the TTL of the key, like Redis PERSIST. Returns false if the key is absent or has no TTL.
+func (c *Cache) Persist(key string) (ok bool) {
+	k := model.KeyOfString(key)
+	entry, found := c.peekValue(&k)
+	if !found || !entry.Persist() {
+		return false
+	}
+	c.requeue(entry)
+	return true
+}
+
+// requeue brings the refresh queue in line with a changed TTL: an entry expired by the change is queued,
+// the queued flag of an entry which is not expired anymore is released (its slot is skipped on dequeue).
+func (c *Cache) requeue(entry *model.Entry) {
+	if entry.IsDueOnTTL(c.cfg) {
+		c.enqueueExpired(entry)
+	} else {
+		entry.DequeueExpired()
+	}
+}
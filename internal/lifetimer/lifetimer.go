@@ -121,7 +121,9 @@ func (w *LifetimeWorker) consumer() {
 		case <-w.ctx.Done():
 			return
 		case entry := <-w.invokeCh:
-			_, err := entry.OnTTL()
+			ctx, cancel := w.refreshCtx()
+			err := w.cache.OnTTL(ctx, entry)
+			cancel()
 			if err == nil {
 				w.counters.affected.Add(1)
 			} else {
@@ -130,3 +132,12 @@ func (w *LifetimeWorker) consumer() {
 		}
 	}
 }
+
+// refreshCtx derives a context of a single TTL callback call from the worker's one,
+// so Close interrupts loaders which are still running.
+func (w *LifetimeWorker) refreshCtx() (context.Context, context.CancelFunc) {
+	if w.cfg.RefreshTimeout > 0 {
+		return context.WithTimeout(w.ctx, w.cfg.RefreshTimeout)
+	}
+	return context.WithCancel(w.ctx)
+}
@@ -0,0 +1,54 @@
+package pool
+
+import (
+	"context"
+)
+
+// Job is a unit of background work; ctx is canceled when the pool is stopped.
+type Job func(ctx context.Context)
+
+// Pool runs jobs on a fixed number of workers. Pending jobs are bounded by the queue size:
+// Submit never blocks and rejects a job when the queue is full.
+type Pool struct {
+	ctx  context.Context
+	jobs chan Job
+}
+
+// New starts workers which exit with ctx.
+func New(ctx context.Context, workers, queueSize int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	p := &Pool{ctx: ctx, jobs: make(chan Job, queueSize)}
+	for i := 0; i < workers; i++ {
+		go p.worker()
+	}
+	return p
+}
+
+// Submit schedules the job. Returns false if the queue is full or the pool is stopped.
+func (p *Pool) Submit(job Job) bool {
+	if p.ctx.Err() != nil {
+		return false
+	}
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Pool) worker() {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case job := <-p.jobs:
+			job(p.ctx)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package pool
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+const timeout, tick = time.Second, time.Millisecond
+
+// TestPool_Submit_RunsJobs runs submitted jobs on workers.
+func TestPool_Submit_RunsJobs(t *testing.T) {
+	p := New(t.Context(), 4, 16)
+
+	var wg sync.WaitGroup
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		require.Eventually(t, func() bool {
+			return p.Submit(func(ctx context.Context) { wg.Done() })
+		}, timeout, tick)
+	}
+	wg.Wait()
+}
+
+// TestPool_Submit_RejectsWhenFull rejects jobs beyond the queue size instead of blocking.
+func TestPool_Submit_RejectsWhenFull(t *testing.T) {
+	p := New(t.Context(), 1, 1)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	require.True(t, p.Submit(func(ctx context.Context) {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	require.True(t, p.Submit(func(ctx context.Context) {}), "queued")
+	require.False(t, p.Submit(func(ctx context.Context) {}), "queue is full")
+	close(release)
+}
+
+// TestPool_Submit_RejectsWhenStopped rejects jobs after ctx is done.
+func TestPool_Submit_RejectsWhenStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	p := New(ctx, 1, 1)
+	cancel()
+
+	require.False(t, p.Submit(func(ctx context.Context) {}))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/Borislavv/go-ash-cache/config"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/Borislavv/go-ash-cache/internal/cache"
@@ -90,6 +91,7 @@ func (l *Logs) loop() {
 
 			common := []any{"interval", l.interval.String()}
 			memBytes := uint64(l.cache.Mem())
+			pinnedBytes := uint64(l.cache.PinnedMem())
 			items := l.cache.Len()
 
 			if l.cfg.Lifetime.Enabled() {
@@ -133,10 +135,85 @@ func (l *Logs) loop() {
 				)
 			}
 
+			if d.coalesced > 0 {
+				l.logger.Info("singleflight",
+					append(common,
+						"coalesced", int64(d.coalesced),
+					)...,
+				)
+			}
+
+			if l.cfg.Negative.Enabled() {
+				l.logger.Info("negative_cache",
+					append(common,
+						"hits", int64(d.negativeHits),
+						"stored", int64(d.negativeStored),
+					)...,
+				)
+			}
+
+			if l.cfg.StaleWhileRevalidate.Enabled() {
+				l.logger.Info("stale_while_revalidate",
+					append(common,
+						"stale_hits", int64(d.staleHits),
+						"revalidated", int64(d.revalidated),
+						"dropped", int64(d.revalidationsDropped),
+					)...,
+				)
+			}
+
+			if l.cfg.Lifetime.Enabled() && l.cfg.Lifetime.StaleIfError.Enabled() {
+				l.logger.Info("stale_if_error",
+					append(common,
+						"refresh_failures", int64(d.refreshFailures),
+						"grace_expired", int64(d.graceExpired),
+					)...,
+				)
+			}
+
+			if d.lazyExpired > 0 {
+				l.logger.Info("lazy_expiry",
+					append(common,
+						"expired", int64(d.lazyExpired),
+					)...,
+				)
+			}
+
+			if d.maxStaleEvicted > 0 {
+				l.logger.Info("max_stale",
+					append(common,
+						"evicted", int64(d.maxStaleEvicted),
+					)...,
+				)
+			}
+
+			if l.cfg.Eviction.Enabled() && l.cfg.Eviction.IsWindowed {
+				window, hitRate := l.cache.WindowSplit()
+				l.logger.Info("window_climber",
+					append(common,
+						"window", fmtShare(window),
+						"main", fmtShare(1-window),
+						"sampled_hit_rate", fmtShare(hitRate),
+						"grown", int64(d.windowGrown),
+						"shrunk", int64(d.windowShrunk),
+						"restarts", int64(d.climbRestarts),
+					)...,
+				)
+			}
+
+			if d.collisions > 0 {
+				l.logger.Info("hash_collisions",
+					append(common,
+						"chained", int64(d.collisions),
+					)...,
+				)
+			}
+
 			l.logger.Info("storage",
 				append(common,
 					"size", bytes.FmtMem(memBytes),
 					"entries", items,
+					"pinned", bytes.FmtMem(pinnedBytes),
 					"soft_limit", softLimit,
 					"hard_limit", hardLimit,
 				)...,
@@ -144,3 +221,8 @@ func (l *Logs) loop() {
 		}
 	}
 }
+
+// fmtShare formats a share (0..1) as percents.
+func fmtShare(share float64) string {
+	return strconv.FormatFloat(share*100, 'f', 2, 64) + "%"
+}
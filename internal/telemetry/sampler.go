@@ -28,6 +28,27 @@ type snapshot struct {
 	hardEvictedItems uint64
 	hardEvictedBytes uint64
 
+	coalesced uint64
+
+	negativeHits   uint64
+	negativeStored uint64
+
+	staleHits            uint64
+	revalidated          uint64
+	revalidationsDropped uint64
+
+	refreshFailures uint64
+	graceExpired    uint64
+
+	collisions uint64
+
+	lazyExpired     uint64
+	maxStaleEvicted uint64
+
+	windowGrown   uint64
+	windowShrunk  uint64
+	climbRestarts uint64
+
 	lifetimeAffected uint64
 	lifetimeErrors   uint64
 	lifetimeScans    uint64
@@ -39,6 +60,14 @@ func (s sampler) snapshot() snapshot {
 	aAllowed, aNotAllowed, hardItems, hardBytes := s.cache.CacheMetrics()
 	softScans, softHits, softItems, softBytes := s.evictor.EvictorMetrics()
 	affected, errs, scans, hits, misses := s.lifetimer.LifetimerMetrics()
+	coalesced := s.cache.FlightMetrics()
+	negHits, negStored := s.cache.NegativeMetrics()
+	staleHits, revalidated, dropped := s.cache.SWRMetrics()
+	refreshFailures, graceExpired := s.cache.StaleIfErrorMetrics()
+	collisions := s.cache.CollisionMetrics()
+	lazyExpired := s.cache.ExpiryMetrics()
+	maxStaleEvicted := s.cache.MaxStaleMetrics()
+	grown, shrunk, restarts := s.cache.ClimberMetrics()
 
 	return snapshot{
 		admissionAllowed:    uint64(max(aAllowed, 0)),
@@ -51,6 +80,27 @@ func (s sampler) snapshot() snapshot {
 		hardEvictedItems: uint64(max(hardItems, 0)),
 		hardEvictedBytes: uint64(max(hardBytes, 0)),
 
+		coalesced: uint64(max(coalesced, 0)),
+
+		negativeHits:   uint64(max(negHits, 0)),
+		negativeStored: uint64(max(negStored, 0)),
+
+		staleHits:            uint64(max(staleHits, 0)),
+		revalidated:          uint64(max(revalidated, 0)),
+		revalidationsDropped: uint64(max(dropped, 0)),
+
+		refreshFailures: uint64(max(refreshFailures, 0)),
+		graceExpired:    uint64(max(graceExpired, 0)),
+
+		collisions: uint64(max(collisions, 0)),
+
+		lazyExpired:     uint64(max(lazyExpired, 0)),
+		maxStaleEvicted: uint64(max(maxStaleEvicted, 0)),
+
+		windowGrown:   uint64(max(grown, 0)),
+		windowShrunk:  uint64(max(shrunk, 0)),
+		climbRestarts: uint64(max(restarts, 0)),
+
 		lifetimeAffected: uint64(max(affected, 0)),
 		lifetimeErrors:   uint64(max(errs, 0)),
 		lifetimeScans:    uint64(max(scans, 0)),
@@ -73,6 +123,27 @@ func deltaSnapshot(prev, cur snapshot) snapshot {
 		softEvictedItems: delta(prev.softEvictedItems, cur.softEvictedItems),
 		softEvictedBytes: delta(prev.softEvictedBytes, cur.softEvictedBytes),
 
+		coalesced: delta(prev.coalesced, cur.coalesced),
+
+		negativeHits:   delta(prev.negativeHits, cur.negativeHits),
+		negativeStored: delta(prev.negativeStored, cur.negativeStored),
+
+		staleHits:            delta(prev.staleHits, cur.staleHits),
+		revalidated:          delta(prev.revalidated, cur.revalidated),
+		revalidationsDropped: delta(prev.revalidationsDropped, cur.revalidationsDropped),
+
+		refreshFailures: delta(prev.refreshFailures, cur.refreshFailures),
+		graceExpired:    delta(prev.graceExpired, cur.graceExpired),
+
+		collisions: delta(prev.collisions, cur.collisions),
+
+		lazyExpired:     delta(prev.lazyExpired, cur.lazyExpired),
+		maxStaleEvicted: delta(prev.maxStaleEvicted, cur.maxStaleEvicted),
+
+		windowGrown:   delta(prev.windowGrown, cur.windowGrown),
+		windowShrunk:  delta(prev.windowShrunk, cur.windowShrunk),
+		climbRestarts: delta(prev.climbRestarts, cur.climbRestarts),
+
 		lifetimeAffected: delta(prev.lifetimeAffected, cur.lifetimeAffected),
 		lifetimeErrors:   delta(prev.lifetimeErrors, cur.lifetimeErrors),
 		lifetimeScans:    delta(prev.lifetimeScans, cur.lifetimeScans),
@@ -10,12 +10,31 @@ type TTLMode int32
 const (
 	TTLModeRefresh TTLMode = iota
 	TTLModeRemove
+	// TTLModeSliding removes an item once it has not been accessed for its TTL.
+	TTLModeSliding
+	// TTLModeStale keeps an expired item and serves it as stale (see GetWithState)
+	// until it is evicted, overwritten or removed.
+	TTLModeStale
 )
 
 type Item interface {
 	Key() *Key
 	SetTTL(ttl time.Duration)
+	// SetExpiresAt sets the TTL so that the item expires at t (a moment in the past expires it at once).
+	SetExpiresAt(t time.Time)
 	SetTTLMode(mode TTLMode)
+	// AddTags attaches tags for InvalidateTag. Tags are taken into account when the item is stored
+	// the first time (by a loader on miss or by an Option); calls made on refresh are ignored.
+	AddTags(tags ...string)
+	// SetMeta attaches a metadata pair to the payload returned by the loader (see GetWithMeta).
+	// A refresh stores the metadata set by the loader during the refresh along with the new payload.
+	SetMeta(key, value string)
+	// SetPriority sets the eviction priority of the item (see Priority).
+	SetPriority(level Priority)
+	// Pin exempts the item from eviction (within db.pinned_fraction of the cache size).
+	// It's taken into account when the item is stored by a load or a write (a write never unpins a stored item),
+	// but not by a refresh; use Cache.Pin afterward.
+	Pin()
 }
 
 type CacheItem interface {
@@ -26,5 +45,6 @@ type CacheItem interface {
 	IsExpired(cfg *config.Cache) bool
 	UpdatedAt() int64
 	TouchedAt() int64
+	Failures() int32
 	Weight() int64
 }
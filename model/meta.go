@@ -0,0 +1,5 @@
+package model
+
+// Meta is metadata stored alongside a payload (e.g. HTTP headers or a content type).
+// Meta returned by the cache is shared and must not be modified.
+type Meta map[string]string
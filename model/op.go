@@ -0,0 +1,26 @@
+package model
+
+// Op tells Compute what to do with the value of the key.
+type Op int32
+
+const (
+	// OpKeep - leave the value as is (an absent key stays absent).
+	OpKeep Op = iota
+	// OpReplace - store the returned value (insert it if the key is absent).
+	OpReplace
+	// OpDelete - remove the key.
+	OpDelete
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpKeep:
+		return "keep"
+	case OpReplace:
+		return "replace"
+	case OpDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Option mutates an Item before it is published by the explicit write API (Set, Add).
+// It receives the same Item a loader callback would, so anything a loader may configure
+// can be configured through an Option as well.
+type Option func(item Item)
+
+// WithTTL overrides the configured TTL of the written item.
+func WithTTL(ttl time.Duration) Option {
+	return func(item Item) { item.SetTTL(ttl) }
+}
+
+// WithExpiresAt makes the written item expire at t.
+func WithExpiresAt(t time.Time) Option {
+	return func(item Item) { item.SetExpiresAt(t) }
+}
+
+// WithTags attaches tags to the written item for InvalidateTag.
+func WithTags(tags ...string) Option {
+	return func(item Item) { item.AddTags(tags...) }
+}
+
+// WithMeta attaches a metadata pair to the written payload.
+func WithMeta(key, value string) Option {
+	return func(item Item) { item.SetMeta(key, value) }
+}
+
+// WithPriority sets the eviction priority of the written item.
+func WithPriority(level Priority) Option {
+	return func(item Item) { item.SetPriority(level) }
+}
+
+// WithPin exempts the written item from eviction.
+func WithPin() Option {
+	return func(item Item) { item.Pin() }
+}
+
+// WithTTLMode overrides the configured TTL mode of the written item.
+func WithTTLMode(mode TTLMode) Option {
+	return func(item Item) { item.SetTTLMode(mode) }
+}
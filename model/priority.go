@@ -0,0 +1,12 @@
+package model
+
+// Priority of an item in eviction: the lower it is, the sooner the item goes.
+// Each level above normal halves the weight of its idle time in sampling and listing eviction,
+// each level below doubles it; wtinylfu eviction goes by frequency only.
+type Priority int32
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
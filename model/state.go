@@ -0,0 +1,23 @@
+package model
+
+// State tells whether a served payload is within its TTL.
+type State int32
+
+const (
+	// StateFresh - the payload is within its TTL (or was just loaded).
+	StateFresh State = iota
+	// StateStale - the payload outlived its TTL and is served while it's being refreshed
+	// (or until it is evicted in the stale TTL mode).
+	StateStale
+)
+
+func (s State) String() string {
+	switch s {
+	case StateFresh:
+		return "fresh"
+	case StateStale:
+		return "stale"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Special results of TTL, the same as Redis TTL returns.
+const (
+	// NoTTL is returned by TTL for a key which never expires (Redis -1).
+	NoTTL time.Duration = -1
+	// NoKey is returned by TTL for an absent key (Redis -2).
+	NoKey time.Duration = -2
+)
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// ItemView is a read-only snapshot of a cached item yielded by the cache iterators.
+// Slices are shared with the cache and must not be modified.
+type ItemView struct {
+	Payload   []byte
+	Meta      Meta     // metadata stored along with the payload
+	RawKey    []byte   // original key; nil unless db.retain_keys is enabled
+	Tags      []string // tags attached by AddTags
+	TTL       time.Duration
+	UpdatedAt int64  // unix nano of the last stored payload
+	TouchedAt int64  // unix nano of the last access
+	Version   uint64 // version for CompareAndSet and CompareAndDelete
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/Borislavv/go-ash-cache/model"
 	"log/slog"
 	"math/rand"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
@@ -88,6 +89,73 @@ func BenchmarkGetHit(b *testing.B) {
 	}
 }
 
+// BenchmarkGetBytesHit measures GetBytes() performance on cache hits (expected: zero allocations)
+func BenchmarkGetBytesHit(b *testing.B) {
+	cache := getBenchCache()
+	key := []byte(benchKeys[0])
+	testData := make([]byte, 1024)
+	callback := func(item model.Item) ([]byte, error) {
+		return testData, nil
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		data, err := cache.GetBytes(key, callback)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(data) == 0 {
+			b.Fatal("empty data")
+		}
+	}
+}
+
+// BenchmarkGetBytesHitParallel measures concurrent GetBytes() performance on hits (expected: zero allocations)
+func BenchmarkGetBytesHitParallel(b *testing.B) {
+	cache := getBenchCache()
+	testData := make([]byte, 1024)
+	callback := func(item model.Item) ([]byte, error) {
+		return testData, nil
+	}
+	keys := make([][]byte, len(benchKeys))
+	for i, key := range benchKeys {
+		keys[i] = []byte(key)
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			data, err := cache.GetBytes(keys[i%len(keys)], callback)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(data) == 0 {
+				b.Fatal("empty data")
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkPeekBytesHit measures PeekBytes() performance on cache hits (expected: zero allocations)
+func BenchmarkPeekBytesHit(b *testing.B) {
+	cache := getBenchCache()
+	key := []byte(benchKeys[0])
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, ok := cache.PeekBytes(key); !ok {
+			b.Fatal("miss")
+		}
+	}
+}
+
 // BenchmarkGetMiss measures Get() performance on cache misses
 func BenchmarkGetMiss(b *testing.B) {
 	cache := getBenchCache()
@@ -267,6 +335,42 @@ func BenchmarkDel(b *testing.B) {
 	}
 }
 
+// BenchmarkDelBytes measures DelBytes() performance
+func BenchmarkDelBytes(b *testing.B) {
+	// no admission control, so re-populated keys are always stored
+	cfg := &config.Cache{DB: config.DBCfg{SizeBytes: 100 * 1024 * 1024}}
+	cfg.AdjustConfig()
+	cache := ashcache.New(b.Context(), cfg, slog.Default())
+	testData := make([]byte, 1024)
+
+	// Keys to delete; re-populated (off the timer) once all of them are deleted,
+	// so each iteration deletes a stored key
+	keys := make([][]byte, 1024)
+	for i := range keys {
+		keys[i] = []byte("del-bytes-" + strconv.Itoa(i))
+	}
+	populate := func() {
+		for _, key := range keys {
+			if !cache.SetBytes(key, testData) {
+				b.Fatal("not stored")
+			}
+		}
+	}
+	populate()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if i > 0 && i%len(keys) == 0 {
+			b.StopTimer()
+			populate()
+			b.StartTimer()
+		}
+		cache.DelBytes(keys[i%len(keys)])
+	}
+}
+
 // BenchmarkConcurrentThroughput measures overall throughput with concurrent operations
 func BenchmarkConcurrentThroughput(b *testing.B) {
 	cache := getBenchCache()
@@ -289,10 +393,12 @@ func BenchmarkConcurrentThroughput(b *testing.B) {
 					return testData, nil
 				})
 				if err != nil {
-					b.Fatal(err)
+					b.Error(err)
+					return
 				}
 				if len(data) == 0 {
-					b.Fatal("empty data")
+					b.Error("empty data")
+					return
 				}
 			}
 		}(g)
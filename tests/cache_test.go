@@ -66,3 +66,55 @@ func TestCacheErrPropagates(t *testing.T) {
 
 	require.Equal(t, uint64(1000), atomic.LoadUint64(&invokes), fmt.Sprintf("expected: 999, actual: %d", invokes))
 }
+
+func TestCacheBytesKeys(t *testing.T) {
+	cache := ashcache.New(t.Context(), help.Cfg(), help.Logger())
+
+	var (
+		invokes  uint64
+		key      = []byte("hello_world")
+		testResp = []byte("test response")
+	)
+	for i := 0; i < 1000; i++ {
+		payload, err := cache.GetBytes(key, func(item model.Item) (resp []byte, respErr error) {
+			atomic.AddUint64(&invokes, 1)
+			return testResp, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, testResp, payload)
+	}
+	require.Equal(t, uint64(1), atomic.LoadUint64(&invokes))
+
+	// bytes and string keys address the same entry
+	payload, ok := cache.Peek("hello_world")
+	require.True(t, ok)
+	require.Equal(t, testResp, payload)
+
+	require.True(t, cache.SetBytes([]byte("written"), []byte("value")))
+	require.False(t, cache.AddBytes([]byte("written"), []byte("other")))
+	payload, ok = cache.PeekBytes([]byte("written"))
+	require.True(t, ok)
+	require.Equal(t, []byte("value"), payload)
+
+	require.True(t, cache.DelBytes([]byte("written")))
+	require.False(t, cache.HasBytes([]byte("written")))
+	require.True(t, cache.HasBytes(key))
+}
+
+func TestCacheBytesKeysHitPathDoesNotAllocate(t *testing.T) {
+	cache := ashcache.New(t.Context(), help.Cfg(), help.Logger())
+
+	var (
+		key      = []byte("hello_world")
+		absent   = []byte("absent")
+		testResp = []byte("test response")
+		callback = func(item model.Item) ([]byte, error) { return testResp, nil }
+	)
+	_, err := cache.GetBytes(key, callback)
+	require.NoError(t, err)
+
+	require.Zero(t, testing.AllocsPerRun(1000, func() { _, _ = cache.GetBytes(key, callback) }))
+	require.Zero(t, testing.AllocsPerRun(1000, func() { _, _ = cache.PeekBytes(key) }))
+	require.Zero(t, testing.AllocsPerRun(1000, func() { _ = cache.HasBytes(key) }))
+	require.Zero(t, testing.AllocsPerRun(1000, func() { _ = cache.DelBytes(absent) }))
+}
@@ -188,3 +188,75 @@ func TestLifetimerStochasticRemoveBeforeTTLExpired(t *testing.T) {
 		}
 	}
 }
+
+func TestLifetimerCloseInterruptsRefresh(t *testing.T) {
+	lifetimerRefreshTestCfg := help.LifetimerRefreshCfg()
+	cache := ashcache.New(t.Context(), lifetimerRefreshTestCfg, help.Logger())
+
+	var (
+		started     = make(chan struct{})
+		interrupted = make(chan error, 1)
+		loads       = &atomic.Int64{}
+	)
+	_, err := cache.GetCtx(t.Context(), "key", func(ctx context.Context, item model.Item) ([]byte, error) {
+		item.SetTTL(time.Millisecond * 100)
+		if loads.Add(1) == 1 {
+			return make([]byte, 128), nil // initial load
+		}
+		if loads.Load() == 2 {
+			close(started)
+		}
+		<-ctx.Done() // slow refresh which respects ctx
+		if loads.Load() == 2 {
+			interrupted <- ctx.Err()
+		}
+		return nil, ctx.Err()
+	})
+	require.NoError(t, err)
+
+	select {
+	case <-started:
+	case <-time.After(time.Second * 10):
+		t.Fatalf("refresh was not started")
+	}
+
+	require.NoError(t, cache.Close())
+
+	select {
+	case err = <-interrupted:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second * 5):
+		t.Fatalf("refresh was not interrupted by Close")
+	}
+}
+
+func TestLifetimerRefreshTimeout(t *testing.T) {
+	lifetimerRefreshTestCfg := help.LifetimerRefreshCfg()
+	lifetimerRefreshTestCfg.Lifetime.RefreshTimeout = time.Millisecond * 50
+	cache := ashcache.New(t.Context(), lifetimerRefreshTestCfg, help.Logger())
+
+	var (
+		timedOut = make(chan error, 1)
+		loads    = &atomic.Int64{}
+	)
+	_, err := cache.GetCtx(t.Context(), "key", func(ctx context.Context, item model.Item) ([]byte, error) {
+		item.SetTTL(time.Millisecond * 100)
+		if loads.Add(1) == 1 {
+			return make([]byte, 128), nil // initial load
+		}
+		<-ctx.Done()
+		select {
+		case timedOut <- ctx.Err():
+		default:
+		}
+		return nil, ctx.Err()
+	})
+	require.NoError(t, err)
+
+	select {
+	case err = <-timedOut:
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(time.Second * 10):
+		t.Fatalf("refresh was not interrupted by timeout")
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"errors"
+	ashcache "github.com/Borislavv/go-ash-cache"
+	"github.com/Borislavv/go-ash-cache/model"
+	"github.com/Borislavv/go-ash-cache/tests/help"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxStaleEvictsEntriesFailingRefreshes(t *testing.T) {
+	cfg := help.LifetimerRefreshCfg()
+	cfg.Lifetime.MaxStale = 500 * time.Millisecond
+	cfg.AdjustConfig()
+	cache := ashcache.New(t.Context(), cfg, help.Logger())
+
+	var failing atomic.Bool
+	loader := func(item model.Item) ([]byte, error) {
+		item.SetTTL(100 * time.Millisecond)
+		if failing.Load() {
+			return nil, errors.New("backend is down")
+		}
+		return []byte("good"), nil
+	}
+
+	_, err := cache.Get("key", loader)
+	require.NoError(t, err)
+	failing.Store(true)
+
+	// past TTL the last good payload is served while refreshes fail
+	time.Sleep(200 * time.Millisecond)
+	data, err := cache.Get("key", loader)
+	require.NoError(t, err)
+	require.Equal(t, "good", string(data))
+
+	// past max stale the lifetimer evicts it, so a read has to reload it and fails
+	require.Eventually(t, func() bool { return cache.MaxStaleMetrics() == 1 }, 3*time.Second, 20*time.Millisecond)
+	require.False(t, cache.Has("key"))
+
+	_, err = cache.Get("key", loader)
+	require.Error(t, err)
+
+	failing.Store(false)
+	data, err = cache.Get("key", loader)
+	require.NoError(t, err)
+	require.Equal(t, "good", string(data))
+}
+
+func TestMaxStaleHidesPayloadFromPeek(t *testing.T) {
+	cfg := help.LifetimerRefreshCfg()
+	cfg.Lifetime.MaxStale = 300 * time.Millisecond
+	cfg.AdjustConfig()
+	cache := ashcache.New(t.Context(), cfg, help.Logger())
+
+	_, err := cache.Get("key", func(item model.Item) ([]byte, error) {
+		item.SetTTL(time.Hour)
+		return []byte("good"), nil
+	})
+	require.NoError(t, err)
+
+	// stop the lifetimer, so the entry stays stored beyond max stale
+	require.NoError(t, cache.Lifetimer.Close())
+
+	data, ok := cache.Peek("key")
+	require.True(t, ok)
+	require.Equal(t, "good", string(data))
+
+	time.Sleep(400 * time.Millisecond)
+	require.Equal(t, int64(1), cache.Len())
+
+	_, ok = cache.Peek("key")
+	require.False(t, ok)
+	require.False(t, cache.Has("key"))
+	require.Equal(t, model.NoKey, cache.TTL("key"))
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"errors"
+	ashcache "github.com/Borislavv/go-ash-cache"
+	"github.com/Borislavv/go-ash-cache/config"
+	"github.com/Borislavv/go-ash-cache/model"
+	"github.com/Borislavv/go-ash-cache/tests/help"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNegativeCachingExpires(t *testing.T) {
+	cfg := help.Cfg()
+	cfg.Negative = &config.NegativeCfg{
+		TTL:   100 * time.Millisecond,
+		Match: func(err error) bool { return err.Error() == "not found" },
+	}
+	cfg.AdjustConfig()
+	cache := ashcache.New(t.Context(), cfg, help.Logger())
+
+	var calls int
+	loader := func(item model.Item) ([]byte, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("not found")
+		}
+		return []byte("value"), nil
+	}
+
+	_, err := cache.Get("key", loader)
+	require.EqualError(t, err, "not found")
+	_, err = cache.Get("key", loader)
+	require.EqualError(t, err, "not found")
+	require.Equal(t, 1, calls)
+
+	// once the tombstone expires the loader is called again
+	require.Eventually(t, func() bool {
+		data, err := cache.Get("key", loader)
+		return err == nil && string(data) == "value"
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Equal(t, 2, calls)
+
+	hits, stored := cache.NegativeMetrics()
+	require.GreaterOrEqual(t, hits, int64(1))
+	require.Equal(t, int64(1), stored)
+}
@@ -0,0 +1,41 @@
+package tests
+
+import (
+	ashcache "github.com/Borislavv/go-ash-cache"
+	"github.com/Borislavv/go-ash-cache/config"
+	"github.com/Borislavv/go-ash-cache/model"
+	"github.com/Borislavv/go-ash-cache/tests/help"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSlidingTTL_RemovesIdleEntries(t *testing.T) {
+	cfg := help.LifetimerRemoveCfg()
+	cfg.Lifetime.OnTTL = config.TTLModeSliding
+	cfg.Lifetime.TTL = 500 * time.Millisecond
+	cfg.AdjustConfig()
+	cache := ashcache.New(t.Context(), cfg, help.Logger())
+
+	loader := func(item model.Item) ([]byte, error) { return []byte("session"), nil }
+	for _, key := range []string{"active", "idle"} {
+		_, err := cache.Get(key, loader)
+		require.NoError(t, err)
+	}
+
+	// the active key is accessed more often than its TTL, so it outlives it several times over
+	for deadline := time.Now().Add(3 * cfg.Lifetime.TTL); time.Now().Before(deadline); {
+		_, err := cache.Get("active", func(item model.Item) ([]byte, error) {
+			t.Fatal("an accessed key must not expire in sliding mode")
+			return nil, nil
+		})
+		require.NoError(t, err)
+		time.Sleep(cfg.Lifetime.TTL / 5)
+	}
+
+	require.Eventually(t, func() bool { return !cache.Has("idle") }, 3*time.Second, 20*time.Millisecond)
+	require.True(t, cache.Has("active"))
+
+	// and once the active key is left alone, it is removed as well
+	require.Eventually(t, func() bool { return !cache.Has("active") }, 3*time.Second, 20*time.Millisecond)
+}
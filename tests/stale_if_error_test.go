@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"errors"
+	ashcache "github.com/Borislavv/go-ash-cache"
+	"github.com/Borislavv/go-ash-cache/config"
+	"github.com/Borislavv/go-ash-cache/model"
+	"github.com/Borislavv/go-ash-cache/tests/help"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStaleIfErrorBacksOffAndExpiresAfterGrace(t *testing.T) {
+	cfg := help.LifetimerRefreshCfg()
+	cfg.Lifetime.StaleIfError = &config.StaleIfErrorCfg{
+		GracePeriod: time.Second,
+		Backoff:     200 * time.Millisecond,
+		MaxBackoff:  200 * time.Millisecond,
+	}
+	cfg.AdjustConfig()
+	cache := ashcache.New(t.Context(), cfg, help.Logger())
+
+	var (
+		calls   atomic.Int64
+		failing atomic.Bool
+	)
+	loader := func(item model.Item) ([]byte, error) {
+		item.SetTTL(100 * time.Millisecond)
+		calls.Add(1)
+		if failing.Load() {
+			return nil, errors.New("backend is down")
+		}
+		return []byte("good"), nil
+	}
+
+	_, err := cache.Get("key", loader)
+	require.NoError(t, err)
+	failing.Store(true)
+
+	// during the grace period the last good payload is served while refreshes are backed off
+	time.Sleep(700 * time.Millisecond)
+	data, err := cache.Get("key", loader)
+	require.NoError(t, err)
+	require.Equal(t, "good", string(data))
+
+	failures, ok := cache.RefreshFailures("key")
+	require.True(t, ok)
+	require.Greater(t, failures, int32(0))
+	require.LessOrEqual(t, calls.Load(), int64(1+5), "failed refreshes must be backed off, not retried on each scan")
+
+	// after the grace period the entry is dropped, so a read reloads it synchronously
+	require.Eventually(t, func() bool {
+		_, graceExpired := cache.StaleIfErrorMetrics()
+		return graceExpired == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	require.False(t, cache.Has("key"))
+
+	failing.Store(false)
+
+	data, err = cache.Get("key", loader)
+	require.NoError(t, err)
+	require.Equal(t, "good", string(data))
+	failures, _ = cache.RefreshFailures("key")
+	require.Zero(t, failures)
+}
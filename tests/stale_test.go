@@ -0,0 +1,54 @@
+package tests
+
+import (
+	ashcache "github.com/Borislavv/go-ash-cache"
+	"github.com/Borislavv/go-ash-cache/config"
+	"github.com/Borislavv/go-ash-cache/model"
+	"github.com/Borislavv/go-ash-cache/tests/help"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStaleModeKeepsAndServesExpiredEntries(t *testing.T) {
+	cfg := help.LifetimerRemoveCfg()
+	cfg.Lifetime.OnTTL = config.TTLModeStale
+	cfg.Lifetime.TTL = 200 * time.Millisecond
+	cfg.AdjustConfig()
+	cache := ashcache.New(t.Context(), cfg, help.Logger())
+
+	var calls atomic.Int64
+	loader := func(item model.Item) ([]byte, error) {
+		calls.Add(1)
+		return []byte("loaded"), nil
+	}
+
+	data, state, err := cache.GetWithState("loaded", loader)
+	require.NoError(t, err)
+	require.Equal(t, "loaded", string(data))
+	require.Equal(t, model.StateFresh, state)
+	require.True(t, cache.Set("written", []byte("written")))
+
+	// neither removed nor refreshed by the lifetimer long after TTL
+	time.Sleep(5 * cfg.Lifetime.TTL)
+	require.True(t, cache.Has("loaded"))
+	require.True(t, cache.Has("written"))
+
+	data, state, err = cache.GetWithState("loaded", loader)
+	require.NoError(t, err)
+	require.Equal(t, "loaded", string(data))
+	require.Equal(t, model.StateStale, state)
+	require.Equal(t, int64(1), calls.Load(), "a stale entry is served without reload")
+
+	_, state, err = cache.GetWithState("written", loader)
+	require.NoError(t, err)
+	require.Equal(t, model.StateStale, state)
+
+	// overwriting the entry makes it fresh again
+	require.True(t, cache.Set("written", []byte("rewritten")))
+	data, state, err = cache.GetWithState("written", loader)
+	require.NoError(t, err)
+	require.Equal(t, "rewritten", string(data))
+	require.Equal(t, model.StateFresh, state)
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"fmt"
+	ashcache "github.com/Borislavv/go-ash-cache"
+	"github.com/Borislavv/go-ash-cache/config"
+	"github.com/Borislavv/go-ash-cache/model"
+	"github.com/Borislavv/go-ash-cache/tests/help"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func swrCfg(window time.Duration) *config.Cache {
+	cfg := help.LifetimerRefreshCfg()
+	cfg.Lifetime.Rate = 1 // keep the lifetimer out of the way
+	cfg.StaleWhileRevalidate = &config.SWRCfg{StaleWindow: window}
+	cfg.AdjustConfig()
+	return cfg
+}
+
+func TestSWRServesStaleAndRevalidatesOnce(t *testing.T) {
+	cache := ashcache.New(t.Context(), swrCfg(time.Minute), help.Logger())
+
+	var calls atomic.Int64
+	loader := func(item model.Item) ([]byte, error) {
+		item.SetTTL(100 * time.Millisecond)
+		return []byte(fmt.Sprintf("v%d", calls.Add(1))), nil
+	}
+
+	data, state, err := cache.GetWithState("key", loader)
+	require.NoError(t, err)
+	require.Equal(t, "v1", string(data))
+	require.Equal(t, model.StateFresh, state)
+
+	time.Sleep(200 * time.Millisecond)
+
+	// within the stale window the old payload is served right away
+	var stale int
+	for i := 0; i < 100; i++ {
+		data, state, err = cache.GetWithState("key", loader)
+		require.NoError(t, err)
+		if state == model.StateStale {
+			require.Equal(t, "v1", string(data))
+			stale++
+		}
+	}
+	require.Greater(t, stale, 0)
+
+	require.Eventually(t, func() bool {
+		data, state, _ = cache.GetWithState("key", loader)
+		return state == model.StateFresh && string(data) == "v2"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, int64(2), calls.Load(), "exactly one background refresh")
+
+	staleHits, revalidated, _ := cache.SWRMetrics()
+	require.GreaterOrEqual(t, staleHits, int64(stale))
+	require.Equal(t, int64(1), revalidated)
+}
+
+func TestSWRReloadsBeyondStaleWindow(t *testing.T) {
+	cache := ashcache.New(t.Context(), swrCfg(50*time.Millisecond), help.Logger())
+
+	var calls atomic.Int64
+	loader := func(item model.Item) ([]byte, error) {
+		item.SetTTL(50 * time.Millisecond)
+		return []byte(fmt.Sprintf("v%d", calls.Add(1))), nil
+	}
+
+	_, err := cache.Get("key", loader)
+	require.NoError(t, err)
+
+	time.Sleep(300 * time.Millisecond)
+
+	// the read blocks on a synchronous reload instead of serving a payload stale beyond the window
+	data, state, err := cache.GetWithState("key", loader)
+	require.NoError(t, err)
+	require.Equal(t, "v2", string(data))
+	require.Equal(t, model.StateFresh, state)
+}
+
+func TestSWRWithoutStaleWindowFallsBackToDefault(t *testing.T) {
+	cfg := swrCfg(0)
+	require.Equal(t, config.DefaultSWRStaleWindow, cfg.StaleWhileRevalidate.StaleWindow)
+	cache := ashcache.New(t.Context(), cfg, help.Logger())
+
+	loader := func(item model.Item) ([]byte, error) {
+		item.SetTTL(100 * time.Millisecond)
+		return []byte("v"), nil
+	}
+	_, err := cache.Get("key", loader)
+	require.NoError(t, err)
+
+	time.Sleep(200 * time.Millisecond)
+
+	// served stale rather than reloaded synchronously
+	data, state, err := cache.GetWithState("key", loader)
+	require.NoError(t, err)
+	require.Equal(t, "v", string(data))
+	require.Equal(t, model.StateStale, state)
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	ashcache "github.com/Borislavv/go-ash-cache"
+	"github.com/Borislavv/go-ash-cache/config"
+	"github.com/Borislavv/go-ash-cache/model"
+	"github.com/Borislavv/go-ash-cache/tests/help"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExpireAndPersist(t *testing.T) {
+	cfg := help.LifetimerRemoveCfg()
+	cfg.AdjustConfig()
+	cache := ashcache.New(t.Context(), cfg, help.Logger())
+
+	require.Equal(t, model.NoKey, cache.TTL("absent"))
+	require.False(t, cache.Expire("absent", time.Second))
+
+	require.True(t, cache.Set("short", []byte("value")))
+	require.True(t, cache.Set("long", []byte("value"), model.WithExpiresAt(time.Now().Add(time.Hour))))
+	require.True(t, cache.Set("persistent", []byte("value")))
+
+	require.InDelta(t, float64(cfg.Lifetime.TTL), float64(cache.TTL("short")), float64(time.Second))
+	require.InDelta(t, float64(time.Hour), float64(cache.TTL("long")), float64(time.Second))
+
+	require.True(t, cache.Persist("persistent"))
+	require.False(t, cache.Persist("persistent"), "the key has no TTL anymore")
+	require.Equal(t, model.NoTTL, cache.TTL("persistent"))
+
+	require.True(t, cache.Expire("short", 200*time.Millisecond))
+	require.LessOrEqual(t, cache.TTL("short"), 200*time.Millisecond)
+
+	// the shortened key is removed by the lifetimer long before the configured TTL
+	require.Eventually(t, func() bool { return !cache.Has("short") }, 3*time.Second, 20*time.Millisecond)
+	require.True(t, cache.Has("long"))
+	require.True(t, cache.Has("persistent"))
+
+	// a moment in the past removes the key
+	require.True(t, cache.ExpireAt("long", time.Now().Add(-time.Second)))
+	require.False(t, cache.Has("long"))
+}
+
+func TestExpiredEntryIsReloadedOnRead(t *testing.T) {
+	cfg := help.Cfg()
+	cfg.Lifetime = nil // nothing removes expired entries but reads
+	cfg.AdjustConfig()
+	cache := ashcache.New(t.Context(), cfg, help.Logger())
+
+	var calls int
+	loader := func(item model.Item) ([]byte, error) {
+		calls++
+		item.SetTTL(100 * time.Millisecond)
+		return []byte("v" + strconv.Itoa(calls)), nil
+	}
+
+	data, err := cache.Get("key", loader)
+	require.NoError(t, err)
+	require.Equal(t, "v1", string(data))
+	require.True(t, cache.SetWithTTL("written", []byte("value"), 100*time.Millisecond))
+
+	time.Sleep(200 * time.Millisecond)
+
+	data, err = cache.Get("key", loader)
+	require.NoError(t, err)
+	require.Equal(t, "v2", string(data), "an expired payload is never served")
+	require.False(t, cache.Has("written"))
+	require.Equal(t, int64(2), cache.ExpiryMetrics())
+}
+
+func TestWrittenEntryExpiresOnReadInRefreshMode(t *testing.T) {
+	cfg := help.LifetimerRefreshCfg()
+	cfg.StaleWhileRevalidate = &config.SWRCfg{StaleWindow: time.Minute}
+	cfg.AdjustConfig()
+	cache := ashcache.New(t.Context(), cfg, help.Logger()) // runs the cached clock
+	require.NoError(t, cache.Lifetimer.Close())            // nothing removes expired entries but reads
+
+	// a written entry has no loader to be refreshed by, so it's removed on TTL
+	require.True(t, cache.SetWithTTL("written", []byte("value"), 100*time.Millisecond))
+	data, ok := cache.Peek("written")
+	require.True(t, ok)
+	require.Equal(t, "value", string(data))
+
+	time.Sleep(200 * time.Millisecond)
+
+	_, ok = cache.Peek("written")
+	require.False(t, ok)
+	require.Equal(t, int64(1), cache.ExpiryMetrics())
+
+	data, state, err := cache.GetWithState("written", func(item model.Item) ([]byte, error) {
+		return []byte("loaded"), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "loaded", string(data), "never served stale")
+	require.NotEqual(t, model.StateStale, state)
+}
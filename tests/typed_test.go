@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Borislavv/go-ash-cache"
+	"github.com/Borislavv/go-ash-cache/model"
+	"github.com/Borislavv/go-ash-cache/tests/help"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type typedUser struct {
+	ID   int
+	Name string
+}
+
+func TestTypedJSON(t *testing.T) {
+	cache := ashcache.New(t.Context(), help.Cfg(), help.Logger())
+	users := ashcache.NewTyped[int, typedUser](cache, ashcache.FmtKey[int], ashcache.JSONCodec[typedUser]{})
+
+	var invokes atomic.Int64
+	for i := 0; i < 100; i++ {
+		user, err := users.Get(123, func(item model.Item) (typedUser, error) {
+			invokes.Add(1)
+			return typedUser{ID: 123, Name: "John"}, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, typedUser{ID: 123, Name: "John"}, user)
+	}
+	require.Equal(t, int64(1), invokes.Load())
+
+	// the stored bytes are the codec's output
+	raw, ok := cache.Peek("123")
+	require.True(t, ok)
+	require.JSONEq(t, `{"ID":123,"Name":"John"}`, string(raw))
+
+	stored, err := users.Set(124, typedUser{ID: 124, Name: "Jane"})
+	require.NoError(t, err)
+	require.True(t, stored)
+
+	user, ok, err := users.Peek(124)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "Jane", user.Name)
+
+	require.True(t, users.Del(124))
+	require.False(t, users.Has(124))
+}
+
+func TestTypedGob(t *testing.T) {
+	cache := ashcache.New(t.Context(), help.Cfg(), help.Logger())
+	users := ashcache.NewTyped[string, typedUser](cache, ashcache.StringKey, ashcache.GobCodec[typedUser]{})
+
+	user, err := users.GetCtx(t.Context(), "user:1", func(ctx context.Context, item model.Item) (typedUser, error) {
+		return typedUser{ID: 1, Name: "John"}, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, typedUser{ID: 1, Name: "John"}, user)
+
+	user, ok, err := users.Peek("user:1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, typedUser{ID: 1, Name: "John"}, user)
+}
+
+func TestTypedPassthrough(t *testing.T) {
+	cache := ashcache.New(t.Context(), help.Cfg(), help.Logger())
+	strs := ashcache.NewTyped[string, string](cache, ashcache.StringKey, ashcache.StringCodec{})
+	raws := ashcache.NewTyped[string, []byte](cache, ashcache.StringKey, ashcache.BytesCodec{})
+
+	stored, err := strs.SetWithTTL("str", "value", time.Minute)
+	require.NoError(t, err)
+	require.True(t, stored)
+
+	raw, ok, err := raws.Peek("str")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("value"), raw)
+
+	stored, err = raws.Add("str", []byte("other"))
+	require.NoError(t, err)
+	require.False(t, stored)
+}
+
+func TestTypedStringCodecFitsEncoded(t *testing.T) {
+	data, err := ashcache.StringCodec{}.Encode(strings.Repeat("x", 33))
+	require.NoError(t, err)
+	require.Len(t, data, 33)
+	require.Equal(t, 33, cap(data), "no spare capacity")
+}
+
+func TestTypedLoaderErrPropagates(t *testing.T) {
+	cache := ashcache.New(t.Context(), help.Cfg(), help.Logger())
+	users := ashcache.NewTyped[int, typedUser](cache, ashcache.FmtKey[int], ashcache.JSONCodec[typedUser]{})
+
+	loadErr := errors.New("not found")
+	_, err := users.Get(1, func(item model.Item) (typedUser, error) {
+		return typedUser{}, loadErr
+	})
+	require.ErrorIs(t, err, loadErr)
+	require.False(t, users.Has(1))
+}
+
+func TestTypedWeightIsEncodedSize(t *testing.T) {
+	cache := ashcache.New(t.Context(), help.Cfg(), help.Logger())
+	users := ashcache.NewTyped[int, typedUser](cache, ashcache.FmtKey[int], ashcache.JSONCodec[typedUser]{})
+	raws := ashcache.NewTyped[int, []byte](cache, ashcache.FmtKey[int], ashcache.BytesCodec{})
+
+	user := typedUser{ID: 1, Name: "John"}
+	encoded, err := ashcache.JSONCodec[typedUser]{}.Encode(user)
+	require.NoError(t, err)
+	require.Equal(t, len(encoded), cap(encoded))
+
+	_, err = users.Set(1, user)
+	require.NoError(t, err)
+	typedMem := cache.Mem()
+	require.True(t, users.Del(1))
+
+	_, err = raws.Set(2, make([]byte, len(encoded)))
+	require.NoError(t, err)
+
+	// the typed entry weighs exactly as a raw entry of the encoded size
+	require.Equal(t, cache.Mem(), typedMem)
+}
+
+func TestTypedRefreshReEncodes(t *testing.T) {
+	cache := ashcache.New(t.Context(), help.LifetimerRefreshCfg(), help.Logger())
+	counters := ashcache.NewTyped[string, int](cache, ashcache.StringKey, ashcache.JSONCodec[int]{})
+
+	var loads atomic.Int64
+	_, err := counters.Get("counter", func(item model.Item) (int, error) {
+		item.SetTTL(time.Millisecond * 100)
+		return int(loads.Add(1)), nil
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second*10)
+	defer cancel()
+
+	checkEach := time.NewTicker(time.Millisecond * 50)
+	defer checkEach.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("context deadline exceeded; test failed")
+		case <-checkEach.C:
+			value, ok, err := counters.Peek("counter")
+			require.NoError(t, err)
+			require.True(t, ok)
+			if value > 1 {
+				raw, _ := cache.Peek("counter")
+				require.Equal(t, fmt.Sprint(value), string(raw))
+				return
+			}
+		}
+	}
+}
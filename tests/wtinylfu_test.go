@@ -0,0 +1,112 @@
+package tests
+
+import (
+	ashcache "github.com/Borislavv/go-ash-cache"
+	"github.com/Borislavv/go-ash-cache/config"
+	"github.com/Borislavv/go-ash-cache/model"
+	"github.com/Borislavv/go-ash-cache/tests/help"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+const hitRatioCapacity = 20_000 // entries of 1KiB
+
+// TestWTinyLFU_HitRatio_BeatsListing replays the same workloads against listing mode with admission control
+// and W-TinyLFU mode: W-TinyLFU must keep more hits on a frequency-skewed workload polluted by scans and
+// on a recency-heavy one, where admission on insert turns new keys away.
+func TestWTinyLFU_HitRatio_BeatsListing(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	zipf := rand.NewZipf(rnd, 1.1, 1, 10*hitRatioCapacity)
+	zipfWithScans := make([]int, 0, 300_000)
+	for i, scanned := 0, 1<<30; i < cap(zipfWithScans); i++ {
+		if i > 50_000 && i%50_000 < 10_000 {
+			zipfWithScans = append(zipfWithScans, scanned) // one-hit wonders
+			scanned++
+		} else {
+			zipfWithScans = append(zipfWithScans, int(zipf.Uint64()))
+		}
+	}
+
+	recency := recencyWorkload(rnd, 300_000)
+
+	for name, workload := range map[string][]int{"zipf with scans": zipfWithScans, "recency": recency} {
+		listing := replayHitRatio(t, newHitRatioCache(t, hitRatioCfg(config.LRUModeListing)), workload)
+		windowed := replayHitRatio(t, newHitRatioCache(t, hitRatioCfg(config.LRUModeWindowed)), workload)
+		t.Logf("%s: listing=%.3f wtinylfu=%.3f", name, listing, windowed)
+		require.Greater(t, windowed, listing, name)
+	}
+}
+
+// TestWTinyLFU_AdaptiveWindow_GrowsOnRecency checks that the window climber grows the window on a recency-heavy
+// workload and keeps more hits than the fixed 1% window.
+func TestWTinyLFU_AdaptiveWindow_GrowsOnRecency(t *testing.T) {
+	workload := recencyWorkload(rand.New(rand.NewSource(1)), 600_000)
+
+	fixed := replayHitRatio(t, newHitRatioCache(t, hitRatioCfg(config.LRUModeWindowed)), workload)
+
+	cfg := hitRatioCfg(config.LRUModeWindowed)
+	cfg.Eviction.AdaptiveWindow = true
+	cache := newHitRatioCache(t, cfg)
+	adaptive := replayHitRatio(t, cache, workload)
+
+	window, sampledHitRate := cache.WindowSplit()
+	grown, shrunk, restarts := cache.ClimberMetrics()
+	t.Logf("fixed=%.3f adaptive=%.3f window=%.4f sampled_hit_rate=%.3f grown=%d shrunk=%d restarts=%d",
+		fixed, adaptive, window, sampledHitRate, grown, shrunk, restarts)
+
+	require.Greater(t, adaptive, fixed)
+	require.Greater(t, window, config.DefaultWindowFraction)
+	require.Greater(t, grown, int64(0))
+	require.Greater(t, sampledHitRate, 0.0)
+}
+
+// recencyWorkload returns n keys of a working set which moves by half of the capacity every 50k requests.
+func recencyWorkload(rnd *rand.Rand, n int) []int {
+	workload := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		workload = append(workload, (i/50_000)*hitRatioCapacity/2+rnd.Intn(hitRatioCapacity*3/4))
+	}
+	return workload
+}
+
+func hitRatioCfg(mode config.LRUMode) *config.Cache {
+	cfg := help.Cfg()
+	cfg.Lifetime = nil
+	cfg.DB.IsTelemetryLogsEnabled = false
+	cfg.DB.SizeBytes = hitRatioCapacity * 1400 // payload + entry overhead
+	cfg.Eviction = &config.EvictionCfg{
+		LRUMode:              mode,
+		SoftLimitCoefficient: 1, // evict on insert only (hard limit) to keep runs comparable
+		CallsPerSec:          1,
+		BackoffSpinsPerCall:  1024,
+	}
+	cfg.AdmissionControl = &config.AdmissionControlCfg{
+		Capacity:            2 * hitRatioCapacity,
+		Shards:              4,
+		MinTableLenPerShard: 64,
+		SampleMultiplier:    10,
+		DoorBitsPerCounter:  2,
+	}
+	cfg.AdjustConfig()
+	return cfg
+}
+
+func newHitRatioCache(t *testing.T, cfg *config.Cache) *ashcache.Cache {
+	return ashcache.New(t.Context(), cfg, help.Logger())
+}
+
+func replayHitRatio(t *testing.T, cache *ashcache.Cache, workload []int) float64 {
+	payload := make([]byte, 1024)
+	misses := 0
+	for _, k := range workload {
+		_, err := cache.Get("key-"+strconv.Itoa(k), func(item model.Item) ([]byte, error) {
+			misses++
+			return payload, nil
+		})
+		require.NoError(t, err)
+	}
+	return 1 - float64(misses)/float64(len(workload))
+}
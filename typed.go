@@ -0,0 +1,105 @@
+package ashcache
+
+import (
+	"context"
+	"fmt"
+	"github.com/Borislavv/go-ash-cache/internal/cache"
+	"github.com/Borislavv/go-ash-cache/model"
+	"time"
+)
+
+// KeyEncoder builds a cache key from a typed key.
+type KeyEncoder[K comparable] func(key K) string
+
+// StringKey is a KeyEncoder for string keys.
+func StringKey(key string) string { return key }
+
+// FmtKey is a KeyEncoder formatting keys by fmt.Sprint.
+func FmtKey[K comparable](key K) string { return fmt.Sprint(key) }
+
+// Typed is a generic façade over a cache: keys are built by a KeyEncoder and values are stored
+// through a Codec. Loader results are encoded on load as well as on every background refresh.
+type Typed[K comparable, V any] struct {
+	cache cache.Cacher
+	key   KeyEncoder[K]
+	codec Codec[V]
+}
+
+// NewTyped wraps the given cache into a typed façade.
+func NewTyped[K comparable, V any](c cache.Cacher, key KeyEncoder[K], codec Codec[V]) *Typed[K, V] {
+	return &Typed[K, V]{cache: c, key: key, codec: codec}
+}
+
+// Get returns the decoded value of the key, calling loader on miss. The loader is kept
+// as the refresh callback, its results are re-encoded on each refresh.
+func (t *Typed[K, V]) Get(key K, loader func(item model.Item) (V, error)) (value V, err error) {
+	data, err := t.cache.Get(t.key(key), func(item model.Item) ([]byte, error) {
+		v, loadErr := loader(item)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		return t.codec.Encode(v)
+	})
+	if err != nil {
+		return value, err
+	}
+	return t.codec.Decode(data)
+}
+
+// GetCtx is a context-aware Get (see cache.Cacher.GetCtx).
+func (t *Typed[K, V]) GetCtx(
+	ctx context.Context,
+	key K,
+	loader func(ctx context.Context, item model.Item) (V, error),
+) (value V, err error) {
+	data, err := t.cache.GetCtx(ctx, t.key(key), func(ctx context.Context, item model.Item) ([]byte, error) {
+		v, loadErr := loader(ctx, item)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		return t.codec.Encode(v)
+	})
+	if err != nil {
+		return value, err
+	}
+	return t.codec.Decode(data)
+}
+
+// Set encodes and stores the value. Returns false if admission control rejected the value.
+func (t *Typed[K, V]) Set(key K, value V, opts ...model.Option) (stored bool, err error) {
+	data, err := t.codec.Encode(value)
+	if err != nil {
+		return false, err
+	}
+	return t.cache.Set(t.key(key), data, opts...), nil
+}
+
+// SetWithTTL is a shortcut for Set(key, value, model.WithTTL(ttl)).
+func (t *Typed[K, V]) SetWithTTL(key K, value V, ttl time.Duration) (stored bool, err error) {
+	return t.Set(key, value, model.WithTTL(ttl))
+}
+
+// Add encodes and stores the value only if the key is absent.
+func (t *Typed[K, V]) Add(key K, value V, opts ...model.Option) (stored bool, err error) {
+	data, err := t.codec.Encode(value)
+	if err != nil {
+		return false, err
+	}
+	return t.cache.Add(t.key(key), data, opts...), nil
+}
+
+// Peek returns the decoded value without running a loader and leaving an access trace.
+func (t *Typed[K, V]) Peek(key K) (value V, ok bool, err error) {
+	data, ok := t.cache.Peek(t.key(key))
+	if !ok {
+		return value, false, nil
+	}
+	value, err = t.codec.Decode(data)
+	return value, err == nil, err
+}
+
+// Has reports whether the key is cached.
+func (t *Typed[K, V]) Has(key K) bool { return t.cache.Has(t.key(key)) }
+
+// Del removes the key.
+func (t *Typed[K, V]) Del(key K) bool { return t.cache.Del(t.key(key)) }